@@ -0,0 +1,130 @@
+package kabestan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	// BaseSeed implements the storage part of SeedExec.
+	// Embed it and call Config with the seed functions, i.e.:
+	//
+	//	type UsersSeed struct {
+	//		kabestan.BaseSeed
+	//	}
+	//
+	//	func NewUsersSeed() *UsersSeed {
+	//		s := &UsersSeed{}
+	//		s.Config(s.Seed, s.Unseed)
+	//		return s
+	//	}
+	BaseSeed struct {
+		seed   SeedFx
+		unseed SeedFx
+		tx     *sqlx.Tx
+		db     DBConn
+		ctx    context.Context
+		cfg    *Config
+	}
+)
+
+// Config sets the seed and unseed functions.
+func (b *BaseSeed) Config(seed SeedFx, unseed SeedFx) {
+	b.seed = seed
+	b.unseed = unseed
+}
+
+// GetSeed returns the seed function.
+func (b *BaseSeed) GetSeed() SeedFx {
+	return b.seed
+}
+
+// GetUnseed returns the unseed function.
+func (b *BaseSeed) GetUnseed() SeedFx {
+	return b.unseed
+}
+
+// SetTx sets the transaction used by the seed functions.
+func (b *BaseSeed) SetTx(tx *sqlx.Tx) {
+	b.tx = tx
+}
+
+// GetTx returns the transaction used by the seed functions.
+func (b *BaseSeed) GetTx() *sqlx.Tx {
+	return b.tx
+}
+
+// SetDB sets seeder database, it allows non
+// transactional seeds to run their statements.
+func (b *BaseSeed) SetDB(db DBConn) {
+	b.db = db
+}
+
+// GetDB returns seeder database,
+// it is nil until the seed is run.
+func (b *BaseSeed) GetDB() DBConn {
+	return b.db
+}
+
+// SetCtx sets the context used by the seed functions.
+func (b *BaseSeed) SetCtx(ctx context.Context) {
+	b.ctx = ctx
+}
+
+// GetCtx returns the context used by the seed functions.
+// It is never nil.
+func (b *BaseSeed) GetCtx() context.Context {
+	if b.ctx == nil {
+		return context.Background()
+	}
+	return b.ctx
+}
+
+// SetCfg sets the config available to the seed functions.
+func (b *BaseSeed) SetCfg(cfg *Config) {
+	b.cfg = cfg
+}
+
+// GetCfg returns seeder config,
+// it is nil until the seed is run.
+func (b *BaseSeed) GetCfg() *Config {
+	return b.cfg
+}
+
+// Savepoint creates a savepoint named name in seed transaction,
+// work done after it can be reverted using RollbackTo
+// without aborting the whole seed.
+func (b *BaseSeed) Savepoint(name string) error {
+	return b.execSavepoint("SAVEPOINT %s;", name)
+}
+
+// RollbackTo reverts seed transaction to savepoint name.
+// The savepoint is kept and can be used again.
+func (b *BaseSeed) RollbackTo(name string) error {
+	return b.execSavepoint("ROLLBACK TO SAVEPOINT %s;", name)
+}
+
+// Release removes savepoint name keeping
+// the work done after it.
+func (b *BaseSeed) Release(name string) error {
+	return b.execSavepoint("RELEASE SAVEPOINT %s;", name)
+}
+
+// execSavepoint runs savepoint statement st on seed transaction.
+// Name is not quoted, it must be a plain identifier
+// so that statement is valid for every engine.
+func (b *BaseSeed) execSavepoint(st, name string) error {
+	if b.tx == nil {
+		return errors.New("no seed transaction")
+	}
+
+	if !identRegex.MatchString(name) {
+		return fmt.Errorf("%w: '%s'", errInvalidIdent, name)
+	}
+
+	_, err := b.tx.ExecContext(b.GetCtx(), fmt.Sprintf(st, name))
+	return err
+}
@@ -2,6 +2,7 @@ package kabestan
 
 import (
 	"bytes"
+	"context"
 	"database/sql/driver"
 	"encoding/binary"
 	"encoding/hex"
@@ -94,6 +95,56 @@ func (pm *pgManager) dbURL() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, pass, db)
 }
 
+// WaitForDB opens a Postgres connection to dsn and waits until
+// it answers, retrying up to retries times with exponential backoff
+// starting at interval, or until context is done.
+func WaitForDB(ctx context.Context, dsn string, retries int, interval time.Duration) (*sqlx.DB, error) {
+	return waitForDB(ctx, "postgres", dsn, retries, interval, nil, nil)
+}
+
+// waitForDB opens a driver connection to dsn and waits until it answers.
+// If set, setup is called on each opened connection before pinging it
+// and notify on each failed attempt.
+func waitForDB(ctx context.Context, driver, dsn string, retries int, interval time.Duration,
+	setup func(db *sqlx.DB), notify backoff.Notify) (*sqlx.DB, error) {
+
+	eb := backoff.NewExponentialBackOff()
+	eb.InitialInterval = interval
+	eb.MaxElapsedTime = 0
+	bo := backoff.WithContext(backoff.WithMaxRetries(eb, uint64(retries)), ctx)
+
+	var db *sqlx.DB
+	op := func() error {
+		conn, err := sqlx.Open(driver, dsn)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+
+		if setup != nil {
+			setup(conn)
+		}
+
+		err = conn.PingContext(ctx)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+
+		db = conn
+		return nil
+	}
+
+	err := backoff.RetryNotify(op, bo, notify)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("connection aborted: %w", ctx.Err())
+		}
+		return nil, err
+	}
+
+	return db, nil
+}
+
 // Misc
 // Point struct
 type Point struct {
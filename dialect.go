@@ -0,0 +1,815 @@
+package kabestan
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type (
+	// dialect encapsulates engine specific statements
+	// and connection strings used by the seeder.
+	dialect interface {
+		DriverName() string
+		DSN(cfg *Config) string
+		AdminDSN(cfg *Config) string
+		DBName(cfg *Config) string
+		Schema(cfg *Config) string
+		RequiredKeys() []string
+		CheckDSN(cfg *Config) error
+		QuoteIdent(name string) string
+		DBExistsSt(name string) (st string, args []interface{})
+		TableExistsSt(schema, table string) (st string, args []interface{})
+		SchemaExistsSt(schema string) (st string, args []interface{})
+		CreateDbSt(name string) string
+		CloneDbSt(name, template string) string
+		DropDbSt(name string) string
+		CloseConnsSt() string
+		CreateSchemaSt(schema string) string
+		ResetSchemaSt(schema string) string
+		CreateSeederTableSt() string
+		UpgradeSeederTableSts() []string
+		SetSchemaSt(schema string) string
+		StatementTimeoutSt(timeout time.Duration) string
+		DropSeederSt(ifExists, cascade bool) string
+		SelSeederSt() string
+		SelChecksumSt() string
+		SelAppliedSt() string
+		RecSeederSt() string
+		DelSeederSt() string
+	}
+
+	pgDialect     struct{}
+	mysqlDialect  struct{}
+	sqliteDialect struct{}
+)
+
+const (
+	pgEngine     = "postgres"
+	mysqlEngine  = "mysql"
+	sqliteEngine = "sqlite"
+)
+
+// newDialect returns the dialect associated to engine.
+// Postgres is used if engine is empty or unknown.
+func newDialect(engine string) dialect {
+	switch strings.ToLower(engine) {
+	case mysqlEngine:
+		return &mysqlDialect{}
+	case sqliteEngine, "sqlite3":
+		return &sqliteDialect{}
+	default:
+		return &pgDialect{}
+	}
+}
+
+// Postgres
+
+const (
+	pgCreateSeederSt = `CREATE TABLE IF NOT EXISTS %s.%s (
+		id UUID PRIMARY KEY,
+		name VARCHAR(64),
+		fx VARCHAR(64),
+		checksum VARCHAR(64),
+		schema_name VARCHAR(64),
+		tags VARCHAR(255),
+ 		is_applied BOOLEAN,
+		created_at TIMESTAMP
+	);`
+
+	pgAddChecksumSt = `ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS checksum VARCHAR(64);`
+
+	pgAddSchemaSt = `ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS schema_name VARCHAR(64);`
+
+	pgAddTagsSt = `ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS tags VARCHAR(255);`
+
+	pgSelSeederSt = `SELECT is_applied FROM %s.%s WHERE name = ? and is_applied = true;`
+
+	pgSelChecksumSt = `SELECT checksum FROM %s.%s WHERE name = ? and is_applied = true ORDER BY created_at DESC LIMIT 1;`
+
+	pgSelAppliedSt = `SELECT name, COALESCE(fx, '') AS fx, created_at FROM %s.%s WHERE is_applied = true ORDER BY created_at;`
+
+	pgRecSeederSt = `INSERT INTO %s.%s (id, name, fx, checksum, schema_name, tags, is_applied, created_at)
+		VALUES (:id, :name, :fx, :checksum, :schema_name, :tags, :is_applied, :created_at);`
+
+	pgDelSeederSt = `DELETE FROM %s.%s WHERE name = ? and is_applied = true;`
+)
+
+func (d *pgDialect) DriverName() string {
+	return "postgres"
+}
+
+// DSN is 'db.url' config value if set,
+// otherwise it is built from 'pg.*' ones.
+func (d *pgDialect) DSN(cfg *Config) string {
+	if u := cfg.ValOrDef("db.url", ""); u != "" {
+		return u
+	}
+
+	host := cfg.ValOrDef("pg.host", "localhost")
+	port := cfg.ValOrDef("pg.port", "5432")
+	schema := cfg.ValOrDef("pg.schema", "public")
+	db := cfg.ValOrDef("pg.database", "kabestan")
+	user := cfg.ValOrDef("pg.user", "kabestan")
+	pass := cfg.ValOrDef("pg.password", "kabestan")
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s search_path=%s%s", host, port, user, pass, db, schema, pgConnParams(cfg))
+}
+
+// AdminDSN uses 'pg.admin*' config values if set,
+// regular ones otherwise, so that database management
+// can be done using a privileged role.
+// If 'db.url' is set it is used replacing its database
+// by 'pg.adminDatabase', 'postgres' by default,
+// it is returned unchanged if it cannot be parsed, see CheckDSN.
+func (d *pgDialect) AdminDSN(cfg *Config) string {
+	if s := cfg.ValOrDef("db.url", ""); s != "" {
+		dsn, err := pgDSNWithDatabase(s, cfg.ValOrDef("pg.adminDatabase", "postgres"))
+		if err != nil {
+			return s
+		}
+		return dsn
+	}
+
+	host := cfg.ValOrDef("pg.adminHost", cfg.ValOrDef("pg.host", "localhost"))
+	port := cfg.ValOrDef("pg.adminPort", cfg.ValOrDef("pg.port", "5432"))
+	schema := "public"
+	db := cfg.ValOrDef("pg.adminDatabase", "postgres")
+	user := cfg.ValOrDef("pg.adminUser", cfg.ValOrDef("pg.user", "kabestan"))
+	pass := cfg.ValOrDef("pg.adminPassword", cfg.ValOrDef("pg.password", "kabestan"))
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s search_path=%s%s", host, port, user, pass, db, schema, pgConnParams(cfg))
+}
+
+// pgDSNDatabase returns the database of a Postgres DSN,
+// either a URL or a key=value connection string,
+// empty if it is not set.
+func pgDSNDatabase(dsn string) (string, error) {
+	if isPgURL(dsn) {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		return strings.Trim(u.Path, "/"), nil
+	}
+
+	params, err := parsePgParams(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	// Last value wins, as for lib/pq.
+	var db string
+	for _, p := range params {
+		if p[0] == "dbname" {
+			db = p[1]
+		}
+	}
+
+	return db, nil
+}
+
+// pgDSNWithDatabase returns a Postgres DSN, either a URL
+// or a key=value connection string, connecting to db
+// instead of its own database.
+func pgDSNWithDatabase(dsn, db string) (string, error) {
+	if isPgURL(dsn) {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		u.Path = "/" + db
+		return u.String(), nil
+	}
+
+	params, err := parsePgParams(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	var sel [][2]string
+	for _, p := range params {
+		if p[0] != "dbname" {
+			sel = append(sel, p)
+		}
+	}
+	sel = append(sel, [2]string{"dbname", db})
+
+	parts := make([]string, len(sel))
+	for i, p := range sel {
+		parts[i] = p[0] + "=" + quotePgParam(p[1])
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// isPgURL tells if dsn is a URL
+// rather than a key=value connection string.
+func isPgURL(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// parsePgParams parses a key=value connection string
+// in order, values can be single quoted and
+// escaped using backslashes.
+func parsePgParams(dsn string) ([][2]string, error) {
+	var params [][2]string
+	r := []rune(dsn)
+
+	for i := 0; i < len(r); {
+		if unicode.IsSpace(r[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(r) && r[i] != '=' && !unicode.IsSpace(r[i]) {
+			i++
+		}
+		key := string(r[start:i])
+		if key == "" {
+			return nil, errors.New("missing key in connection string")
+		}
+
+		for i < len(r) && unicode.IsSpace(r[i]) {
+			i++
+		}
+		if i >= len(r) || r[i] != '=' {
+			return nil, fmt.Errorf("missing '=' after '%s' in connection string", key)
+		}
+		i++
+
+		for i < len(r) && unicode.IsSpace(r[i]) {
+			i++
+		}
+
+		var val strings.Builder
+		quoted := i < len(r) && r[i] == '\''
+		if quoted {
+			i++
+		}
+
+		closed := false
+		for i < len(r) {
+			c := r[i]
+			if c == '\\' && i+1 < len(r) {
+				val.WriteRune(r[i+1])
+				i += 2
+				continue
+			}
+			if quoted && c == '\'' {
+				closed = true
+				i++
+				break
+			}
+			if !quoted && unicode.IsSpace(c) {
+				break
+			}
+			val.WriteRune(c)
+			i++
+		}
+
+		if quoted && !closed {
+			return nil, fmt.Errorf("unterminated quoted value of '%s' in connection string", key)
+		}
+
+		params = append(params, [2]string{key, val.String()})
+	}
+
+	return params, nil
+}
+
+// quotePgParam quotes a connection string value
+// if it is empty or contains spaces, quotes or backslashes.
+func quotePgParam(val string) string {
+	if val != "" && !strings.ContainsAny(val, " \t\n'\\") {
+		return val
+	}
+
+	val = strings.Replace(val, `\`, `\\`, -1)
+	val = strings.Replace(val, `'`, `\'`, -1)
+	return "'" + val + "'"
+}
+
+// pgConnParams returns TLS and extra DSN parameters.
+// SSL mode defaults to disable, certificate paths
+// are only included if set, values are quoted if needed,
+// and 'pg.extraParams' is appended verbatim.
+func pgConnParams(cfg *Config) string {
+	params := " sslmode=" + quotePgParam(cfg.ValOrDef("pg.sslmode", "disable"))
+
+	for _, k := range []string{"sslrootcert", "sslcert", "sslkey"} {
+		if v := cfg.ValOrDef("pg."+k, ""); v != "" {
+			params += fmt.Sprintf(" %s=%s", k, quotePgParam(v))
+		}
+	}
+
+	if extra := cfg.ValOrDef("pg.extraParams", ""); extra != "" {
+		params += " " + extra
+	}
+
+	return params
+}
+
+// DBName is taken from 'db.url' config value if set,
+// either its URL path or its 'dbname' key,
+// otherwise from 'pg.database', 'kabestan' by default as in DSN.
+func (d *pgDialect) DBName(cfg *Config) string {
+	if s := cfg.ValOrDef("db.url", ""); s != "" {
+		db, err := pgDSNDatabase(s)
+		if err == nil && db != "" {
+			return db
+		}
+	}
+
+	return cfg.ValOrDef("pg.database", "kabestan")
+}
+
+// Schema is 'pg.schema' config value,
+// 'public' by default as in DSN.
+func (d *pgDialect) Schema(cfg *Config) string {
+	return cfg.ValOrDef("pg.schema", "public")
+}
+
+func (d *pgDialect) RequiredKeys() []string {
+	return []string{"pg.host", "pg.database", "pg.user"}
+}
+
+// CheckDSN returns an error if 'db.url' config value
+// is set but it cannot be parsed, as a URL or as
+// a key=value connection string, or it has no database.
+func (d *pgDialect) CheckDSN(cfg *Config) error {
+	s := cfg.ValOrDef("db.url", "")
+	if s == "" {
+		return nil
+	}
+
+	db, err := pgDSNDatabase(s)
+	if err != nil {
+		return fmt.Errorf("invalid 'db.url': %w", err)
+	}
+
+	if db == "" {
+		return errors.New("invalid 'db.url': no database name")
+	}
+
+	return nil
+}
+
+func (d *pgDialect) QuoteIdent(name string) string {
+	return quoteIdent(name)
+}
+
+func (d *pgDialect) DBExistsSt(name string) (string, []interface{}) {
+	return `SELECT EXISTS(
+		SELECT datname FROM pg_catalog.pg_database WHERE lower(datname) = lower(?));`, []interface{}{name}
+}
+
+func (d *pgDialect) TableExistsSt(schema, table string) (string, []interface{}) {
+	return `SELECT EXISTS (
+		SELECT 1
+   	FROM   pg_catalog.pg_class c
+   	JOIN   pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+   	WHERE  n.nspname = ?
+   	AND    c.relname = ?
+   	AND    c.relkind = 'r'
+	);`, []interface{}{schema, table}
+}
+
+func (d *pgDialect) SchemaExistsSt(schema string) (string, []interface{}) {
+	return `SELECT EXISTS(
+		SELECT 1 FROM pg_catalog.pg_namespace WHERE nspname = ?);`, []interface{}{schema}
+}
+
+func (d *pgDialect) CreateDbSt(name string) string {
+	return fmt.Sprintf(pgCreateDbSt, d.QuoteIdent(name))
+}
+
+func (d *pgDialect) CloneDbSt(name, template string) string {
+	return fmt.Sprintf(`CREATE DATABASE %s TEMPLATE %s;`, d.QuoteIdent(name), d.QuoteIdent(template))
+}
+
+func (d *pgDialect) DropDbSt(name string) string {
+	return fmt.Sprintf(pgDropDbSt, d.QuoteIdent(name))
+}
+
+func (d *pgDialect) CloseConnsSt() string {
+	return `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = ? AND pid <> pg_backend_pid();`
+}
+
+// CreateSchemaSt returns an empty statement for
+// public schema, it always exists.
+func (d *pgDialect) CreateSchemaSt(schema string) string {
+	if schema == "public" {
+		return ""
+	}
+	return fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s;`, d.QuoteIdent(schema))
+}
+
+// ResetSchemaSt drops schema, and all objects it contains,
+// before creating it again.
+func (d *pgDialect) ResetSchemaSt(schema string) string {
+	return fmt.Sprintf(`DROP SCHEMA IF EXISTS %s CASCADE; CREATE SCHEMA %s;`, d.QuoteIdent(schema), d.QuoteIdent(schema))
+}
+
+func (d *pgDialect) CreateSeederTableSt() string {
+	return pgCreateSeederSt
+}
+
+// UpgradeSeederTableSts adds the columns missing
+// in seeder tables created by previous versions.
+func (d *pgDialect) UpgradeSeederTableSts() []string {
+	return []string{pgAddChecksumSt, pgAddSchemaSt, pgAddTagsSt}
+}
+
+// SetSchemaSt sets the schema used by the
+// current transaction only.
+func (d *pgDialect) SetSchemaSt(schema string) string {
+	return fmt.Sprintf(`SET LOCAL search_path TO %s;`, d.QuoteIdent(schema))
+}
+
+// StatementTimeoutSt limits the time each statement
+// of the current transaction is allowed to run.
+func (d *pgDialect) StatementTimeoutSt(timeout time.Duration) string {
+	return fmt.Sprintf(`SET LOCAL statement_timeout = %d;`, timeout.Milliseconds())
+}
+
+func (d *pgDialect) DropSeederSt(ifExists, cascade bool) string {
+	return dropTableSt(ifExists, cascade)
+}
+
+func (d *pgDialect) SelSeederSt() string {
+	return pgSelSeederSt
+}
+
+func (d *pgDialect) SelChecksumSt() string {
+	return pgSelChecksumSt
+}
+
+func (d *pgDialect) SelAppliedSt() string {
+	return pgSelAppliedSt
+}
+
+func (d *pgDialect) RecSeederSt() string {
+	return pgRecSeederSt
+}
+
+func (d *pgDialect) DelSeederSt() string {
+	return pgDelSeederSt
+}
+
+// MySQL
+// Database driver is not imported by this package,
+// i.e.: import _ "github.com/go-sql-driver/mysql"
+
+const (
+	mysqlCreateSeederSt = `CREATE TABLE IF NOT EXISTS %s.%s (
+		id CHAR(36) PRIMARY KEY,
+		name VARCHAR(64),
+		fx VARCHAR(64),
+		checksum VARCHAR(64),
+		schema_name VARCHAR(64),
+		tags VARCHAR(255),
+		is_applied BOOLEAN,
+		created_at DATETIME
+	);`
+)
+
+func (d *mysqlDialect) DriverName() string {
+	return "mysql"
+}
+
+func (d *mysqlDialect) DSN(cfg *Config) string {
+	host := cfg.ValOrDef("mysql.host", "localhost")
+	port := cfg.ValOrDef("mysql.port", "3306")
+	db := cfg.ValOrDef("mysql.database", "kabestan")
+	user := cfg.ValOrDef("mysql.user", "kabestan")
+	pass := cfg.ValOrDef("mysql.password", "kabestan")
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", user, pass, host, port, db)
+}
+
+func (d *mysqlDialect) AdminDSN(cfg *Config) string {
+	host := cfg.ValOrDef("mysql.host", "localhost")
+	port := cfg.ValOrDef("mysql.port", "3306")
+	user := cfg.ValOrDef("mysql.user", "kabestan")
+	pass := cfg.ValOrDef("mysql.password", "kabestan")
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/?parseTime=true", user, pass, host, port)
+}
+
+// DBName is 'mysql.database' config value,
+// 'kabestan' by default as in DSN.
+func (d *mysqlDialect) DBName(cfg *Config) string {
+	return cfg.ValOrDef("mysql.database", "kabestan")
+}
+
+// Schema in MySQL is a synonym of database.
+func (d *mysqlDialect) Schema(cfg *Config) string {
+	return d.DBName(cfg)
+}
+
+func (d *mysqlDialect) RequiredKeys() []string {
+	return []string{"mysql.host", "mysql.database", "mysql.user"}
+}
+
+// CheckDSN always succeeds, 'db.url' is not used for MySQL.
+func (d *mysqlDialect) CheckDSN(cfg *Config) error {
+	return nil
+}
+
+// QuoteIdent uses backticks, MySQL only accepts
+// double quoted identifiers in ANSI_QUOTES mode.
+func (d *mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func (d *mysqlDialect) DBExistsSt(name string) (string, []interface{}) {
+	return `SELECT EXISTS(
+		SELECT 1 FROM information_schema.schemata WHERE schema_name = ?);`, []interface{}{name}
+}
+
+func (d *mysqlDialect) TableExistsSt(schema, table string) (string, []interface{}) {
+	return `SELECT EXISTS(
+		SELECT 1 FROM information_schema.tables WHERE table_schema = ? AND table_name = ?);`, []interface{}{schema, table}
+}
+
+func (d *mysqlDialect) SchemaExistsSt(schema string) (string, []interface{}) {
+	return d.DBExistsSt(schema)
+}
+
+func (d *mysqlDialect) CreateDbSt(name string) string {
+	return fmt.Sprintf(`CREATE DATABASE %s;`, d.QuoteIdent(name))
+}
+
+// CloneDbSt is not supported for MySQL.
+func (d *mysqlDialect) CloneDbSt(name, template string) string {
+	return ""
+}
+
+func (d *mysqlDialect) DropDbSt(name string) string {
+	return fmt.Sprintf(`DROP DATABASE %s;`, d.QuoteIdent(name))
+}
+
+// CloseConnsSt is not required for MySQL.
+func (d *mysqlDialect) CloseConnsSt() string {
+	return ""
+}
+
+// CreateSchemaSt is not required for MySQL,
+// schema is the seeder database.
+func (d *mysqlDialect) CreateSchemaSt(schema string) string {
+	return ""
+}
+
+// ResetSchemaSt is not supported for MySQL,
+// schema is the seeder database.
+func (d *mysqlDialect) ResetSchemaSt(schema string) string {
+	return ""
+}
+
+func (d *mysqlDialect) CreateSeederTableSt() string {
+	return mysqlCreateSeederSt
+}
+
+// UpgradeSeederTableSts is not supported for MySQL,
+// seeder tables created by previous versions
+// have to be recreated.
+func (d *mysqlDialect) UpgradeSeederTableSts() []string {
+	return nil
+}
+
+// SetSchemaSt is not supported for MySQL.
+func (d *mysqlDialect) SetSchemaSt(schema string) string {
+	return ""
+}
+
+// StatementTimeoutSt is not supported for MySQL,
+// its timeout cannot be limited to a transaction.
+func (d *mysqlDialect) StatementTimeoutSt(timeout time.Duration) string {
+	return ""
+}
+
+func (d *mysqlDialect) DropSeederSt(ifExists, cascade bool) string {
+	return dropTableSt(ifExists, cascade)
+}
+
+func (d *mysqlDialect) SelSeederSt() string {
+	return pgSelSeederSt
+}
+
+func (d *mysqlDialect) SelChecksumSt() string {
+	return pgSelChecksumSt
+}
+
+func (d *mysqlDialect) SelAppliedSt() string {
+	return pgSelAppliedSt
+}
+
+func (d *mysqlDialect) RecSeederSt() string {
+	return pgRecSeederSt
+}
+
+func (d *mysqlDialect) DelSeederSt() string {
+	return pgDelSeederSt
+}
+
+// SQLite
+// Database driver is not imported by this package,
+// i.e.: import _ "github.com/mattn/go-sqlite3"
+
+const (
+	sqliteCreateSeederSt = `CREATE TABLE IF NOT EXISTS %s.%s (
+		id TEXT PRIMARY KEY,
+		name VARCHAR(64),
+		fx VARCHAR(64),
+		checksum VARCHAR(64),
+		schema_name VARCHAR(64),
+		tags VARCHAR(255),
+		is_applied BOOLEAN,
+		created_at TIMESTAMP
+	);`
+)
+
+func (d *sqliteDialect) DriverName() string {
+	return "sqlite3"
+}
+
+// DSN for SQLite is the database file path.
+func (d *sqliteDialect) DSN(cfg *Config) string {
+	return cfg.ValOrDef("sqlite.file", "kabestan.db")
+}
+
+// AdminDSN for SQLite is the same as DSN
+// database file is created on connection.
+func (d *sqliteDialect) AdminDSN(cfg *Config) string {
+	return d.DSN(cfg)
+}
+
+func (d *sqliteDialect) DBName(cfg *Config) string {
+	return d.DSN(cfg)
+}
+
+// Schema in SQLite always refers to main database.
+func (d *sqliteDialect) Schema(cfg *Config) string {
+	return "main"
+}
+
+// RequiredKeys returns no keys,
+// database file defaults to 'kabestan.db'.
+func (d *sqliteDialect) RequiredKeys() []string {
+	return nil
+}
+
+// CheckDSN always succeeds, 'db.url' is not used for SQLite.
+func (d *sqliteDialect) CheckDSN(cfg *Config) error {
+	return nil
+}
+
+func (d *sqliteDialect) QuoteIdent(name string) string {
+	return quoteIdent(name)
+}
+
+// DBExistsSt always evaluates to true
+// SQLite creates the database file on connection.
+func (d *sqliteDialect) DBExistsSt(name string) (string, []interface{}) {
+	return `SELECT 1;`, nil
+}
+
+func (d *sqliteDialect) TableExistsSt(schema, table string) (string, []interface{}) {
+	st := fmt.Sprintf(`SELECT EXISTS(
+		SELECT 1 FROM %s.sqlite_master WHERE type = 'table' AND name = ?);`, d.QuoteIdent(schema))
+	return st, []interface{}{table}
+}
+
+// SchemaExistsSt always evaluates to true,
+// main schema always exists.
+func (d *sqliteDialect) SchemaExistsSt(schema string) (string, []interface{}) {
+	return `SELECT 1;`, nil
+}
+
+// CreateDbSt is a no-op for SQLite.
+func (d *sqliteDialect) CreateDbSt(name string) string {
+	return `SELECT 1;`
+}
+
+// CloneDbSt is not supported for SQLite,
+// database file can be copied instead.
+func (d *sqliteDialect) CloneDbSt(name, template string) string {
+	return ""
+}
+
+// DropDbSt is a no-op for SQLite.
+func (d *sqliteDialect) DropDbSt(name string) string {
+	return `SELECT 1;`
+}
+
+// CloseConnsSt is not required for SQLite.
+func (d *sqliteDialect) CloseConnsSt() string {
+	return ""
+}
+
+// CreateSchemaSt is not required for SQLite,
+// main schema always exists.
+func (d *sqliteDialect) CreateSchemaSt(schema string) string {
+	return ""
+}
+
+// ResetSchemaSt is not supported for SQLite.
+func (d *sqliteDialect) ResetSchemaSt(schema string) string {
+	return ""
+}
+
+func (d *sqliteDialect) CreateSeederTableSt() string {
+	return sqliteCreateSeederSt
+}
+
+// UpgradeSeederTableSts is not supported for SQLite,
+// seeder tables created by previous versions
+// have to be recreated.
+func (d *sqliteDialect) UpgradeSeederTableSts() []string {
+	return nil
+}
+
+// SetSchemaSt is not supported for SQLite.
+func (d *sqliteDialect) SetSchemaSt(schema string) string {
+	return ""
+}
+
+// StatementTimeoutSt is not supported for SQLite.
+func (d *sqliteDialect) StatementTimeoutSt(timeout time.Duration) string {
+	return ""
+}
+
+// DropSeederSt ignores cascade,
+// SQLite doesn't support it.
+func (d *sqliteDialect) DropSeederSt(ifExists, cascade bool) string {
+	return dropTableSt(ifExists, false)
+}
+
+func (d *sqliteDialect) SelSeederSt() string {
+	return pgSelSeederSt
+}
+
+func (d *sqliteDialect) SelChecksumSt() string {
+	return pgSelChecksumSt
+}
+
+func (d *sqliteDialect) SelAppliedSt() string {
+	return pgSelAppliedSt
+}
+
+func (d *sqliteDialect) RecSeederSt() string {
+	return pgRecSeederSt
+}
+
+func (d *sqliteDialect) DelSeederSt() string {
+	return pgDelSeederSt
+}
+
+// dropTableSt returns a drop table statement
+// to be formatted with schema and table names.
+func dropTableSt(ifExists, cascade bool) string {
+	st := "DROP TABLE "
+	if ifExists {
+		st += "IF EXISTS "
+	}
+
+	st += "%s.%s"
+	if cascade {
+		st += " CASCADE"
+	}
+
+	return st + ";"
+}
+
+// Identifiers
+
+var (
+	errInvalidIdent = errors.New("invalid identifier")
+)
+
+// validIdent returns an error if name
+// cannot be used as an SQL identifier.
+func validIdent(name string) error {
+	if strings.TrimSpace(name) == "" || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("%w: '%s'", errInvalidIdent, name)
+	}
+	return nil
+}
+
+// quoteIdent returns name quoted as an SQL identifier
+// so it can be safely interpolated in a statement.
+// Embedded quotes are escaped.
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// quoteQualIdent quotes each dot separated
+// part of a schema qualified name using quote.
+func quoteQualIdent(quote func(string) string, name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = quote(p)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,280 @@
+package kabestan
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	// DiffSeedExec is a SeedExec that inserts only the rows
+	// whose key columns values are not already present in table.
+	// Existing rows are left untouched.
+	// It is applied again whenever its rows change.
+	DiffSeedExec struct {
+		table   string
+		keyCols []string
+		rows    []map[string]interface{}
+		tx      *sqlx.Tx
+		ctx     context.Context
+		cfg     *Config
+		dia     dialect
+		hook    StatementHook
+		// Inserted is the number of rows inserted
+		// by the last execution.
+		Inserted int64
+		// Skipped is the number of rows already present
+		// in the last execution.
+		Skipped int64
+	}
+)
+
+// NewDiffSeedExec returns a seed executor that inserts
+// the rows missing from table, keyed by keyCols.
+func NewDiffSeedExec(table string, keyCols []string, rows []map[string]interface{}) *DiffSeedExec {
+	return &DiffSeedExec{
+		table:   table,
+		keyCols: keyCols,
+		rows:    rows,
+		ctx:     context.Background(),
+	}
+}
+
+// AddDiffSeed registers a seed that inserts
+// the rows missing from table, keyed by keyCols.
+// The executor is returned so that its counters can be read.
+func (s *Seeder) AddDiffSeed(table string, keyCols []string, rows []map[string]interface{}) *DiffSeedExec {
+	e := NewDiffSeedExec(table, keyCols, rows)
+	s.AddSeed(e)
+	return e
+}
+
+// Config is a no-op, diff seeds
+// always insert their missing rows.
+func (e *DiffSeedExec) Config(seed SeedFx, unseed SeedFx) {
+}
+
+// GetSeed returns the seed function.
+func (e *DiffSeedExec) GetSeed() SeedFx {
+	return e.Seed
+}
+
+// GetUnseed returns the unseed function.
+func (e *DiffSeedExec) GetUnseed() SeedFx {
+	return e.Unseed
+}
+
+// SetTx sets the transaction used to insert the rows.
+func (e *DiffSeedExec) SetTx(tx *sqlx.Tx) {
+	e.tx = tx
+}
+
+// GetTx returns the executor transaction.
+func (e *DiffSeedExec) GetTx() *sqlx.Tx {
+	return e.tx
+}
+
+// SetCtx sets the context used to insert the rows.
+func (e *DiffSeedExec) SetCtx(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// SetCfg sets the config used to read 'seed.batchSize'.
+func (e *DiffSeedExec) SetCfg(cfg *Config) {
+	e.cfg = cfg
+}
+
+// setDialect sets the dialect used to quote identifiers.
+func (e *DiffSeedExec) setDialect(d dialect) {
+	e.dia = d
+}
+
+// setStatementHook sets the hook called after each statement.
+func (e *DiffSeedExec) setStatementHook(h StatementHook) {
+	e.hook = h
+}
+
+// RecordName is the table name followed by 'diff'.
+func (e *DiffSeedExec) RecordName() string {
+	return fmt.Sprintf("%s.diff", e.table)
+}
+
+// Version is a hash of table, key columns and rows,
+// so that rows added later are detected.
+func (e *DiffSeedExec) Version() string {
+	b, err := json.Marshal(struct {
+		Table   string
+		KeyCols []string
+		Rows    []map[string]interface{}
+	}{e.table, e.keyCols, e.rows})
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// ReapplyOnChange is true, existing rows are skipped
+// so only the rows added since last execution are inserted.
+func (e *DiffSeedExec) ReapplyOnChange() bool {
+	return true
+}
+
+// RowsAffected returns the rows inserted by last execution.
+func (e *DiffSeedExec) RowsAffected() int64 {
+	return e.Inserted
+}
+
+// Unseed is a no-op, inserted rows are not removed.
+// Only its record is removed from seeder table.
+func (e *DiffSeedExec) Unseed() error {
+	return nil
+}
+
+// Seed reads existing keys and inserts the missing rows in batches.
+// Keys are compared using the text representation
+// of their driver values, see diffKey.
+func (e *DiffSeedExec) Seed() error {
+	if len(e.keyCols) == 0 {
+		return fmt.Errorf("no key columns for '%s' diff seed", e.table)
+	}
+
+	existing, err := e.existingKeys()
+	if err != nil {
+		return fmt.Errorf("cannot read '%s' existing keys: %w", e.table, err)
+	}
+
+	e.Inserted = 0
+	e.Skipped = 0
+
+	var missing []map[string]interface{}
+	for _, r := range e.rows {
+		vals := make([]interface{}, len(e.keyCols))
+		for i, c := range e.keyCols {
+			vals[i] = r[c]
+		}
+
+		k := diffKey(vals)
+		if existing[k] {
+			e.Skipped++
+			continue
+		}
+
+		// Duplicated input rows are only inserted once.
+		existing[k] = true
+		missing = append(missing, r)
+	}
+
+	cols, rows := mapRows(missing)
+	size := batchSize(e.cfg, len(cols))
+
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		st, args, err := insertSt(e.dia, e.table, cols, rows[start:end])
+		if err != nil {
+			return fmt.Errorf("cannot insert '%s' seed data: %w", e.table, err)
+		}
+
+		res, err := execSt(e.ctx, e.tx, e.hook, e.tx.Rebind(st), args...)
+		if err != nil {
+			return fmt.Errorf("cannot insert '%s' seed data: %w", e.table, err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			n = int64(end - start)
+		}
+		e.Inserted += n
+	}
+
+	return nil
+}
+
+// existingKeys returns the key columns values present in table.
+func (e *DiffSeedExec) existingKeys() (map[string]bool, error) {
+	qc := make([]string, len(e.keyCols))
+	for i, c := range e.keyCols {
+		qc[i] = e.quote(c)
+	}
+
+	st := fmt.Sprintf("SELECT %s FROM %s;", strings.Join(qc, ", "), quoteQualIdent(e.quote, e.table))
+
+	start := time.Now()
+	r, err := e.tx.QueryContext(e.ctx, st)
+	if e.hook != nil {
+		e.hook(st, nil, time.Since(start), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	keys := map[string]bool{}
+	for r.Next() {
+		kv := make([]interface{}, len(e.keyCols))
+		dest := make([]interface{}, len(kv))
+		for i := range kv {
+			dest[i] = &kv[i]
+		}
+
+		err = r.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[diffKey(kv)] = true
+	}
+
+	return keys, r.Err()
+}
+
+// quote quotes name as an identifier using executor dialect.
+func (e *DiffSeedExec) quote(name string) string {
+	return dialectQuote(e.dia, name)
+}
+
+// diffKey returns the text representation of key values.
+// Values are converted to driver values first, so that
+// input ones and the ones scanned from table match.
+func diffKey(vals []interface{}) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			dv = fmt.Sprint(v)
+		}
+
+		parts[i] = keyText(dv)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// keyText formats driver value v, nil is
+// kept apart from any text value.
+func keyText(v driver.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "\x01"
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,98 @@
+package kabestan
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffSeedVersion(t *testing.T) {
+	rows := []map[string]interface{}{{"code": "es", "name": "Spanish"}}
+	v1 := NewDiffSeedExec("langs", []string{"code"}, rows).Version()
+
+	same := NewDiffSeedExec("langs", []string{"code"}, []map[string]interface{}{{"name": "Spanish", "code": "es"}})
+	if v := same.Version(); v != v1 {
+		t.Errorf("same rows have different versions: %q, %q", v, v1)
+	}
+
+	rows = append(rows, map[string]interface{}{"code": "en", "name": "English"})
+	if v := NewDiffSeedExec("langs", []string{"code"}, rows).Version(); v == v1 {
+		t.Error("added row does not change version")
+	}
+}
+
+func TestDiffSeedReappliedOnChange(t *testing.T) {
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+
+	e := s.AddDiffSeed("langs", []string{"code"}, []map[string]interface{}{{"code": "es"}})
+	sd := s.seeds[0]
+
+	var stored string
+	f.query = func(st string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		if strings.Contains(st, "SELECT checksum") {
+			return []string{"checksum"}, [][]driver.Value{{stored}}, nil
+		}
+		return []string{"is_applied"}, [][]driver.Value{{true}}, nil
+	}
+
+	tests := []struct {
+		stored string
+		want   bool
+	}{
+		{checksum(e), false},
+		{"outdated", true},
+	}
+
+	for _, tt := range tests {
+		stored = tt.stored
+
+		pending, err := s.isPending(sd)
+		if err != nil {
+			t.Fatalf("isPending error: %v", err)
+		}
+
+		if pending != tt.want {
+			t.Errorf("stored checksum %q: pending = %v, want %v", tt.stored, pending, tt.want)
+		}
+	}
+}
+
+func TestDiffSeedNonStringKeys(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	f, db := newFakeDB()
+	f.query = func(st string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		if strings.Contains(st, `SELECT "id", "at", "active"`) {
+			return []string{"id", "at", "active"}, [][]driver.Value{{int64(1), at, true}}, nil
+		}
+		return []string{"is_applied"}, nil, nil
+	}
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+
+	local := at.In(time.FixedZone("UTC+2", 2*60*60))
+	e := s.AddDiffSeed("events", []string{"id", "at", "active"}, []map[string]interface{}{
+		{"id": float64(1), "at": local, "active": true},
+		{"id": 2, "at": at, "active": false},
+	})
+
+	res := s.applySeed(context.Background(), s.seeds[0])
+	if res.Err != nil {
+		t.Fatalf("seed error: %v", res.Err)
+	}
+
+	if e.Skipped != 1 {
+		t.Errorf("skipped %d rows, want existing one skipped", e.Skipped)
+	}
+
+	ins := f.stmts(`INSERT INTO "events"`)
+	if len(ins) != 1 || len(ins[0].args) != 3 {
+		t.Fatalf("inserts = %v, want only the missing row", ins)
+	}
+}
@@ -1,10 +1,40 @@
 package kabestan
 
+import (
+	"errors"
+	"fmt"
+)
+
 type (
 	Err struct {
 		msgID string
 		Err   error
 	}
+
+	// SeedError is a failed operation on a seed.
+	SeedError struct {
+		Name string
+		Op   string
+		Err  error
+	}
+)
+
+var (
+	// ErrSeedNotFound is returned when a seed is not registered.
+	ErrSeedNotFound = errors.New("seed not found")
+	// ErrDBNotExist is returned when seeder database
+	// does not exist and it cannot be created.
+	ErrDBNotExist = errors.New("database does not exist")
+	// ErrSeedAlreadyApplied is returned when a single
+	// seed is requested but it was already applied.
+	ErrSeedAlreadyApplied = errors.New("seed already applied")
+	// ErrSeedTableMissing is returned when seeder table
+	// is required but it has not been created yet.
+	ErrSeedTableMissing = errors.New("seeder table does not exist")
+	// ErrSeedSkipped can be returned by a seed function
+	// that found nothing to do, it is not considered a failure
+	// and the seed is recorded as applied.
+	ErrSeedSkipped = errors.New("seed skipped")
 )
 
 func NewErr(msgID string, err error) Err {
@@ -25,3 +55,12 @@ func (e Err) Error() string {
 func (e Err) MsgID() string {
 	return e.msgID
 }
+
+func (e SeedError) Error() string {
+	return fmt.Sprintf("cannot %s seed '%s': %v", e.Op, e.Name, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e SeedError) Unwrap() error {
+	return e.Err
+}
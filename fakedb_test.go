@@ -0,0 +1,194 @@
+package kabestan
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	// fakeDB is an in memory database/sql driver that records
+	// the statements it receives, so that seeder can be tested
+	// without a database server.
+	fakeDB struct {
+		mu sync.Mutex
+		// execs are the statements executed, in order.
+		execs []fakeExec
+		// query, if set, returns the result of a query,
+		// a single false value otherwise.
+		query func(st string, args []driver.Value) (cols []string, rows [][]driver.Value, err error)
+		// exec, if set, returns the error of a statement.
+		exec func(st string, args []driver.Value) error
+		// Transaction counters.
+		begun, committed, rolledBack int
+	}
+
+	fakeExec struct {
+		st   string
+		args []driver.Value
+	}
+
+	fakeConnector struct{ db *fakeDB }
+	fakeDriver    struct{ db *fakeDB }
+	fakeConn      struct{ db *fakeDB }
+	fakeTx        struct{ db *fakeDB }
+
+	fakeStmt struct {
+		db *fakeDB
+		st string
+	}
+
+	fakeRows struct {
+		cols []string
+		rows [][]driver.Value
+		i    int
+	}
+
+	fakeResult struct{ n int64 }
+)
+
+// newFakeDB returns a fake database and
+// a connection to it using Postgres bind vars.
+func newFakeDB() (*fakeDB, *sqlx.DB) {
+	f := &fakeDB{}
+	return f, sqlx.NewDb(sql.OpenDB(fakeConnector{f}), "postgres")
+}
+
+// stmts returns the executed statements containing sub.
+func (f *fakeDB) stmts(sub string) []fakeExec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var sel []fakeExec
+	for _, e := range f.execs {
+		if strings.Contains(e.st, sub) {
+			sel = append(sel, e)
+		}
+	}
+	return sel
+}
+
+// open returns the number of transactions neither committed nor reverted.
+func (f *fakeDB) open() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.begun - f.committed - f.rolledBack
+}
+
+func (c fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return fakeConn{c.db}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return fakeDriver{c.db}
+}
+
+func (d fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{d.db}, nil
+}
+
+func (c fakeConn) Prepare(st string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, st: st}, nil
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.db.begun++
+	return fakeTx{c.db}, nil
+}
+
+func (t fakeTx) Commit() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+
+	t.db.committed++
+	return nil
+}
+
+func (t fakeTx) Rollback() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+
+	t.db.rolledBack++
+	return nil
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+// NumInput is unknown so that any number of arguments is accepted.
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	s.db.execs = append(s.db.execs, fakeExec{st: s.st, args: args})
+	exec := s.db.exec
+	s.db.mu.Unlock()
+
+	if exec != nil {
+		if err := exec(s.st, args); err != nil {
+			return nil, err
+		}
+	}
+
+	return fakeResult{1}, nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	query := s.db.query
+	s.db.mu.Unlock()
+
+	if query == nil {
+		return &fakeRows{cols: []string{"exists"}, rows: [][]driver.Value{{false}}}, nil
+	}
+
+	cols, rows, err := query(s.st, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fakeRows{cols: cols, rows: rows}, nil
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.cols
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	return 0, errors.New("not supported")
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return r.n, nil
+}
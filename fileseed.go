@@ -0,0 +1,353 @@
+package kabestan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	// FileSeedExec is a SeedExec that runs
+	// the statements contained in an SQL file.
+	// File is read from fsys if set, from disk otherwise.
+	FileSeedExec struct {
+		fsys fs.FS
+		path string
+		tx   *sqlx.Tx
+		ctx  context.Context
+		log  Logger
+		hook StatementHook
+		rows int64
+		// Template
+		isTmpl bool
+		data   interface{}
+	}
+)
+
+// NewFileSeedExec returns a seed executor for the SQL file at path.
+func NewFileSeedExec(path string) *FileSeedExec {
+	return &FileSeedExec{
+		path: path,
+		ctx:  context.Background(),
+	}
+}
+
+// NewFSSeedExec returns a seed executor for the SQL file at path in fsys.
+func NewFSSeedExec(fsys fs.FS, path string) *FileSeedExec {
+	return &FileSeedExec{
+		fsys: fsys,
+		path: path,
+		ctx:  context.Background(),
+	}
+}
+
+// AddSeedFile registers an SQL file seed.
+// File is read when seeding.
+func (s *Seeder) AddSeedFile(path string) {
+	s.AddSeed(NewFileSeedExec(path))
+}
+
+// AddSeedTemplate registers an SQL file seed
+// rendered as a template against data before running.
+func (s *Seeder) AddSeedTemplate(path string, data interface{}) {
+	s.AddSeed(NewFileSeedExec(path).WithTemplate(data))
+}
+
+// WithTemplate makes the file to be rendered as a
+// text/template against data before splitting it in statements,
+// data is usually a map or seeder *Config,
+// i.e.: {{ .ValOrDef "seed.tenantID" "1" }}.
+// Rendered values are not escaped, templates are meant for
+// identifiers and literals controlled by the seed author.
+func (e *FileSeedExec) WithTemplate(data interface{}) *FileSeedExec {
+	e.isTmpl = true
+	e.data = data
+	return e
+}
+
+// AddSeedDir registers every '.sql' file in dir
+// as a seed, sorted lexically by name.
+func (s *Seeder) AddSeedDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("cannot read seed dir '%s': %w", dir, err)
+	}
+
+	sort.Strings(files)
+
+	for _, f := range files {
+		s.AddSeedFile(f)
+	}
+
+	return nil
+}
+
+// AddSeedFS registers every file in fsys matching glob
+// as a seed, sorted lexically by name, i.e.: an embed.FS.
+// Files are read when seeding.
+func (s *Seeder) AddSeedFS(fsys fs.FS, glob string) error {
+	files, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return fmt.Errorf("cannot read seed files '%s': %w", glob, err)
+	}
+
+	sort.Strings(files)
+
+	for _, f := range files {
+		s.AddSeed(NewFSSeedExec(fsys, f))
+	}
+
+	return nil
+}
+
+// Config is a no-op, file seeds
+// always run the statements in its file.
+func (e *FileSeedExec) Config(seed SeedFx, unseed SeedFx) {
+}
+
+// GetSeed returns the seed function.
+func (e *FileSeedExec) GetSeed() SeedFx {
+	return e.Seed
+}
+
+// GetUnseed returns the unseed function.
+func (e *FileSeedExec) GetUnseed() SeedFx {
+	return e.Unseed
+}
+
+// SetTx sets the transaction used to run the statements.
+func (e *FileSeedExec) SetTx(tx *sqlx.Tx) {
+	e.tx = tx
+}
+
+// GetTx returns the executor transaction.
+func (e *FileSeedExec) GetTx() *sqlx.Tx {
+	return e.tx
+}
+
+// SetCtx sets the context used to run the statements.
+func (e *FileSeedExec) SetCtx(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// RowsAffected returns the rows affected by
+// all statements in last execution.
+func (e *FileSeedExec) RowsAffected() int64 {
+	return e.rows
+}
+
+// SetLog sets the logger used to trace executed statements.
+func (e *FileSeedExec) SetLog(log Logger) {
+	e.log = log
+}
+
+// setStatementHook sets the hook called after each statement.
+func (e *FileSeedExec) setStatementHook(h StatementHook) {
+	e.hook = h
+}
+
+// RecordName is the file base name.
+func (e *FileSeedExec) RecordName() string {
+	if e.fsys != nil {
+		return path.Base(e.path)
+	}
+	return filepath.Base(e.path)
+}
+
+// Seed reads the file and runs its statements.
+func (e *FileSeedExec) Seed() error {
+	src, err := e.read()
+	if err != nil {
+		return fmt.Errorf("cannot read seed file '%s': %w", e.path, err)
+	}
+
+	if e.isTmpl {
+		src, err = e.render(src)
+		if err != nil {
+			return fmt.Errorf("cannot render seed file '%s': %w", e.path, err)
+		}
+	}
+
+	e.rows = 0
+
+	for _, st := range splitSQL(string(src)) {
+		res, err := execSt(e.ctx, e.tx, e.hook, st)
+		if err != nil {
+			return fmt.Errorf("seed file '%s' statement failed: %w", e.path, err)
+		}
+
+		// Not all drivers and statements report it.
+		if n, err := res.RowsAffected(); err == nil {
+			e.rows += n
+		}
+
+		if e.log != nil {
+			e.log.Debug("Seed statement executed", "file", e.path, "statement", st)
+		}
+	}
+
+	return nil
+}
+
+// Version returns seed file contents, rendered
+// against its data for templates, so that any change
+// to them is detected.
+// It is empty if the file cannot be read or rendered,
+// the error is reported when seeding.
+func (e *FileSeedExec) Version() string {
+	src, err := e.read()
+	if err != nil {
+		return ""
+	}
+
+	if e.isTmpl {
+		src, err = e.render(src)
+		if err != nil {
+			return ""
+		}
+	}
+
+	return string(src)
+}
+
+func (e *FileSeedExec) render(src []byte) ([]byte, error) {
+	t, err := template.New(e.path).Option("missingkey=error").Parse(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	err = t.Execute(&b, e.data)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+func (e *FileSeedExec) read() ([]byte, error) {
+	if e.fsys != nil {
+		return fs.ReadFile(e.fsys, e.path)
+	}
+	return ioutil.ReadFile(e.path)
+}
+
+// Unseed is a no-op, file seeds cannot be reverted.
+// Only its record is removed from seeder table.
+func (e *FileSeedExec) Unseed() error {
+	return nil
+}
+
+// splitSQL splits src into statements at semicolons
+// that are not inside quotes, comments or dollar quoted strings.
+// Empty statements are discarded.
+func splitSQL(src string) []string {
+	var sts []string
+	var sb strings.Builder
+
+	flush := func() {
+		st := strings.TrimSpace(sb.String())
+		if st != "" {
+			sts = append(sts, st)
+		}
+		sb.Reset()
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			j := closingQuote(src, i, c)
+			sb.WriteString(src[i:j])
+			i = j - 1
+
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			j := strings.IndexByte(src[i:], '\n')
+			if j < 0 {
+				j = len(src) - i
+			}
+			sb.WriteString(src[i : i+j])
+			i += j - 1
+
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			j := strings.Index(src[i+2:], "*/")
+			end := len(src)
+			if j >= 0 {
+				end = i + 2 + j + 2
+			}
+			sb.WriteString(src[i:end])
+			i = end - 1
+
+		case c == '$':
+			tag, ok := dollarTag(src[i:])
+			if !ok {
+				sb.WriteByte(c)
+				continue
+			}
+			j := strings.Index(src[i+len(tag):], tag)
+			end := len(src)
+			if j >= 0 {
+				end = i + len(tag) + j + len(tag)
+			}
+			sb.WriteString(src[i:end])
+			i = end - 1
+
+		case c == ';':
+			flush()
+
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	flush()
+	return sts
+}
+
+// closingQuote returns the index right after
+// the quote that closes the one at start.
+// Doubled quotes are considered escaped.
+func closingQuote(src string, start int, q byte) int {
+	for i := start + 1; i < len(src); i++ {
+		if src[i] != q {
+			continue
+		}
+
+		if i+1 < len(src) && src[i+1] == q {
+			i++
+			continue
+		}
+
+		return i + 1
+	}
+
+	return len(src)
+}
+
+// dollarTag returns the Postgres dollar quote tag
+// ($$ or $tag$) at the start of src, if any.
+func dollarTag(src string) (string, bool) {
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+
+		if c == '$' {
+			return src[:i+1], true
+		}
+
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,23 @@
+package kabestan
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileSeedTemplateVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tenant.sql": {Data: []byte("INSERT INTO tenants (id) VALUES ({{ .ID }});")},
+	}
+
+	v1 := NewFSSeedExec(fsys, "tenant.sql").WithTemplate(map[string]int{"ID": 1}).Version()
+	v2 := NewFSSeedExec(fsys, "tenant.sql").WithTemplate(map[string]int{"ID": 2}).Version()
+
+	if v1 == "" || v1 == v2 {
+		t.Errorf("template data change not detected: %q, %q", v1, v2)
+	}
+
+	if v := NewFSSeedExec(fsys, "tenant.sql").WithTemplate(map[string]int{}).Version(); v != "" {
+		t.Errorf("version of template that cannot be rendered = %q, want empty", v)
+	}
+}
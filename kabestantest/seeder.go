@@ -0,0 +1,102 @@
+// Package kabestantest provides test doubles for kabestan types.
+package kabestantest
+
+import (
+	"sync"
+
+	"gitlab.com/kabestan/backend/kabestan"
+)
+
+type (
+	// FakeSeeder is a kabestan.SeederIF that records
+	// its calls instead of touching a database.
+	// It is safe for concurrent use.
+	FakeSeeder struct {
+		mx          sync.Mutex
+		seedErr     error
+		unseedErr   error
+		seedCalls   int
+		unseedCalls int
+	}
+)
+
+var _ kabestan.SeederIF = (*FakeSeeder)(nil)
+
+// NewFakeSeeder returns a fake seeder
+// whose calls always succeed.
+func NewFakeSeeder() *FakeSeeder {
+	return &FakeSeeder{}
+}
+
+// Seed records the call and returns the canned seed error, if any.
+func (f *FakeSeeder) Seed() error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.seedCalls++
+	return f.seedErr
+}
+
+// Unseed records the call and returns the canned unseed error, if any.
+func (f *FakeSeeder) Unseed() error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.unseedCalls++
+	return f.unseedErr
+}
+
+// SetSeedErr sets the error returned by subsequent Seed calls.
+// A nil err makes them succeed again.
+func (f *FakeSeeder) SetSeedErr(err error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.seedErr = err
+}
+
+// SetUnseedErr sets the error returned by subsequent Unseed calls.
+// A nil err makes them succeed again.
+func (f *FakeSeeder) SetUnseedErr(err error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.unseedErr = err
+}
+
+// SeedCalls returns the number of times Seed was called.
+func (f *FakeSeeder) SeedCalls() int {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return f.seedCalls
+}
+
+// UnseedCalls returns the number of times Unseed was called.
+func (f *FakeSeeder) UnseedCalls() int {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return f.unseedCalls
+}
+
+// SeedCalled reports whether Seed was called exactly n times.
+func (f *FakeSeeder) SeedCalled(n int) bool {
+	return f.SeedCalls() == n
+}
+
+// UnseedCalled reports whether Unseed was called exactly n times.
+func (f *FakeSeeder) UnseedCalled(n int) bool {
+	return f.UnseedCalls() == n
+}
+
+// Reset clears recorded calls and canned errors.
+func (f *FakeSeeder) Reset() {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.seedErr = nil
+	f.unseedErr = nil
+	f.seedCalls = 0
+	f.unseedCalls = 0
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -331,6 +332,53 @@ func stringify(val interface{}) string {
 	}
 }
 
+// levelLogger is a Logger that discards
+// messages below its level.
+type levelLogger struct {
+	Logger
+	level int
+}
+
+// newLevelLogger returns l limited to the level named by name:
+// 'quiet' (errors only), 'info' or 'debug'.
+// l is returned as is if name is empty or unknown.
+func newLevelLogger(l Logger, name string) Logger {
+	level := DebugLevel
+	switch strings.ToLower(name) {
+	case "quiet":
+		level = ErrorLevel
+	case "info":
+		level = InfoLevel
+	case "debug":
+		level = DebugLevel
+	default:
+		return l
+	}
+
+	return &levelLogger{Logger: l, level: level}
+}
+
+// Debug logs debug messages.
+func (l *levelLogger) Debug(meta ...interface{}) {
+	if l.level <= DebugLevel {
+		l.Logger.Debug(meta...)
+	}
+}
+
+// Info logs info messages.
+func (l *levelLogger) Info(meta ...interface{}) {
+	if l.level <= InfoLevel {
+		l.Logger.Info(meta...)
+	}
+}
+
+// Warn logs warning messages.
+func (l *levelLogger) Warn(meta ...interface{}) {
+	if l.level <= WarnLevel {
+		l.Logger.Warn(meta...)
+	}
+}
+
 // UpdateLogLevel updates log level.
 func (l Log) UpdateLogLevel(level int) {
 	// Allow info level to log the update
@@ -164,6 +164,17 @@ func (m *Migrator) dbExists() bool {
 
 // migExists returns true if migrations table exists.
 func (m *Migrator) migTableExists() bool {
+	exists, err := m.checkMigTable()
+	if err != nil {
+		m.Log.Error(err, "Error checking database")
+		return false
+	}
+
+	return exists
+}
+
+// checkMigTable returns true if migrations table exists.
+func (m *Migrator) checkMigTable() (bool, error) {
 	st := fmt.Sprintf(`SELECT EXISTS (
 		SELECT 1
    	FROM   pg_catalog.pg_class c
@@ -171,25 +182,24 @@ func (m *Migrator) migTableExists() bool {
    	WHERE  n.nspname = '%s'
    	AND    c.relname = '%s'
    	AND    c.relkind = 'r'
-	);`, m.schema, m.dbName)
+	);`, m.schema, pgMigrationsTable)
 
 	r, err := m.DB.Query(st)
 	if err != nil {
-		m.Log.Error(err, "Error checking database")
-		return false
+		return false, err
 	}
+	defer r.Close()
 
 	for r.Next() {
 		var exists sql.NullBool
 		err = r.Scan(&exists)
 		if err != nil {
-			m.Log.Error(err, "Cannot read query result: %s\n")
-			return false
+			return false, fmt.Errorf("cannot read query result: %w", err)
 		}
 
-		return exists.Bool
+		return exists.Bool, nil
 	}
-	return false
+	return false, r.Err()
 }
 
 // CreateDb migration.
@@ -454,27 +464,76 @@ func (m *Migrator) cancelRollback(name string) bool {
 	return true
 }
 
-func (m *Migrator) canApplyMigration(name string) bool {
-	st := fmt.Sprintf(pgSelMigrationSt, m.schema, pgMigrationsTable, name)
-	r, err := m.DB.Query(st)
+// PendingMigrations returns the name of the
+// registered migrations not applied yet.
+func (m *Migrator) PendingMigrations() ([]string, error) {
+	if m.DB == nil {
+		err := m.pgConnect()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	exists, err := m.checkMigTable()
+	if err != nil {
+		return nil, fmt.Errorf("cannot check migrations table: %w", err)
+	}
+
+	pending := []string{}
+	if !exists {
+		for _, mg := range m.migs {
+			pending = append(pending, migName(getFxName(mg.Executor.GetUp())))
+		}
+		return pending, nil
+	}
+
+	for _, mg := range m.migs {
+		name := migName(getFxName(mg.Executor.GetUp()))
 
+		can, err := m.migrationPending(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot determine migration '%s' status: %w", name, err)
+		}
+
+		if can {
+			pending = append(pending, name)
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *Migrator) canApplyMigration(name string) bool {
+	can, err := m.migrationPending(name)
 	if err != nil {
 		m.Log.Info("Cannot determine migration status: %s\n", err.Error())
 		return false
 	}
 
+	return can
+}
+
+// migrationPending returns true if there is
+// no applied record for the migration.
+func (m *Migrator) migrationPending(name string) (bool, error) {
+	st := fmt.Sprintf(pgSelMigrationSt, m.schema, pgMigrationsTable, name)
+	r, err := m.DB.Query(st)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
 	for r.Next() {
 		var applied sql.NullBool
 		err = r.Scan(&applied)
 		if err != nil {
-			m.Log.Info("Cannot determine migration status: %s\n", err.Error())
-			return false
+			return false, err
 		}
 
-		return !applied.Bool
+		return !applied.Bool, nil
 	}
 
-	return true
+	return true, r.Err()
 }
 
 func (m *Migrator) delMigration(e Exec) error {
@@ -498,10 +557,28 @@ func (m *Migrator) last() (last int) {
 	return m.count() - 1
 }
 
+// getFxName returns the method name of a method value.
+// For other functions (closures, package level functions)
+// the last element of its runtime name is returned,
+// i.e.: 'func1' for an anonymous function.
 func getFxName(i interface{}) string {
-	n := runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name()
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+
+	n := runtime.FuncForPC(v.Pointer()).Name()
 	t := strings.FieldsFunc(n, split)
-	return t[len(t)-2]
+	if len(t) == 0 {
+		return ""
+	}
+
+	// Method values are suffixed with '-fm'
+	if strings.HasSuffix(n, "-fm") && len(t) > 1 {
+		return t[len(t)-2]
+	}
+
+	return t[len(t)-1]
 }
 
 func split(r rune) bool {
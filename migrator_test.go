@@ -0,0 +1,41 @@
+package kabestan
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestPendingMigrationsQueryError(t *testing.T) {
+	f, db := newFakeDB()
+	f.query = func(st string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		return nil, nil, errors.New("connection lost")
+	}
+
+	m := NewMigrator(testConfig(map[string]string{"app.env": "test"}), testLogger{}, "test-migrator", db)
+
+	pending, err := m.PendingMigrations()
+	if err == nil {
+		t.Fatalf("expected an error, got pending migrations %v", pending)
+	}
+}
+
+func TestSeedChecksMigrationsFirst(t *testing.T) {
+	f, db := newFakeDB()
+	f.query = func(st string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		return nil, nil, errors.New("connection lost")
+	}
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+	s.RequireMigrations(NewMigrator(s.Cfg, testLogger{}, "test-migrator", db))
+
+	err := s.Seed()
+	if err == nil {
+		t.Fatal("expected a migrations error")
+	}
+
+	if n := len(f.stmts("")); n > 0 {
+		t.Errorf("%d statements executed before checking migrations", n)
+	}
+}
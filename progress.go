@@ -0,0 +1,50 @@
+package kabestan
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type (
+	// Progress is notified of seeding advance.
+	// Start is called with the number of seeds to process,
+	// Step before each one of them and Done at the end,
+	// even if seeding fails.
+	Progress interface {
+		Start(total int)
+		Step(name string, i int)
+		Done()
+	}
+
+	// StdoutProgress is a Progress that
+	// prints seeding advance to stdout.
+	StdoutProgress struct {
+		// Out defaults to os.Stdout.
+		Out   io.Writer
+		total int
+	}
+)
+
+// Start reports the number of seeds to process.
+func (p *StdoutProgress) Start(total int) {
+	p.total = total
+	fmt.Fprintf(p.out(), "Seeding %d seeds\n", total)
+}
+
+// Step reports that i-th seed (zero based) is about to be processed.
+func (p *StdoutProgress) Step(name string, i int) {
+	fmt.Fprintf(p.out(), "[%d/%d] %s\n", i+1, p.total, name)
+}
+
+// Done reports that seeding ended.
+func (p *StdoutProgress) Done() {
+	fmt.Fprintln(p.out(), "Seeding done")
+}
+
+func (p *StdoutProgress) out() io.Writer {
+	if p.Out == nil {
+		return os.Stdout
+	}
+	return p.Out
+}
@@ -0,0 +1,114 @@
+package kabestan
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+const seedTmpl = `package {{.Pkg}}
+
+import (
+	"gitlab.com/kabestan/backend/kabestan"
+)
+
+// {{.Type}} seed.
+type {{.Type}} struct {
+	kabestan.BaseSeed
+}
+
+// New{{.Type}} returns a {{.Type}} ready to be added to a seeder.
+func New{{.Type}}() *{{.Type}} {
+	s := &{{.Type}}{}
+	s.Config(s.{{.Fx}}, s.Unseed{{.Fx}})
+	return s
+}
+
+// {{.Fx}} seeds the database.
+func (s *{{.Type}}) {{.Fx}}() error {
+	// Use s.GetTx() and s.GetCtx() to insert seed data.
+	return nil
+}
+
+// Unseed{{.Fx}} reverts {{.Fx}} seed.
+func (s *{{.Type}}) Unseed{{.Fx}}() error {
+	return nil
+}
+`
+
+// ScaffoldSeed writes to dir a new Go file with a SeedExec
+// embedding BaseSeed whose seed function is named after name,
+// i.e.: 'create_users' generates a CreateUsers method
+// and a CreateUsersSeed type in create_users.go file.
+// Package name is taken from dir base name.
+// Existing files are not overwritten.
+func ScaffoldSeed(dir, name string) (path string, err error) {
+	fx := toCamelCase(name)
+	if fx == "" {
+		return "", fmt.Errorf("invalid seed name: '%s'", name)
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid seed dir: %w", err)
+	}
+
+	pkg := strings.ToLower(toCamelCase(filepath.Base(abs)))
+	if pkg == "" || !unicode.IsLetter(rune(pkg[0])) {
+		pkg = "seeds"
+	}
+
+	var buf bytes.Buffer
+	t := template.Must(template.New("seed").Parse(seedTmpl))
+	err = t.Execute(&buf, map[string]string{
+		"Pkg":  pkg,
+		"Type": fx + "Seed",
+		"Fx":   fx,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot generate seed: %w", err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("cannot format seed: %w", err)
+	}
+
+	path = filepath.Join(dir, toSnakeCase(fx)+".go")
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", fmt.Errorf("cannot create seed file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(src)
+	if err != nil {
+		return "", fmt.Errorf("cannot write seed file: %w", err)
+	}
+
+	return path, f.Close()
+}
+
+// toCamelCase joins the alphanumeric words
+// in str capitalizing the first letter of each one,
+// i.e.: 'create_users' becomes 'CreateUsers'.
+func toCamelCase(str string) string {
+	words := strings.FieldsFunc(str, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+
+	return strings.TrimLeftFunc(b.String(), unicode.IsDigit)
+}
@@ -1,10 +1,18 @@
 package kabestan
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -14,28 +22,275 @@ import (
 type (
 	SeederIF interface {
 		Seed() error
+		Unseed() error
 	}
 )
 
+const (
+	// PhaseStructure seeds create data other seeds rely on.
+	PhaseStructure = "structure"
+	// PhaseData seeds insert application data.
+	PhaseData = "data"
+)
+
 type (
 	// Fx type alias
 	SeedFx = func() error
 
+	// DBConn is the database connection used by Seeder,
+	// *sqlx.DB satisfies it.
+	DBConn interface {
+		Query(query string, args ...interface{}) (*sql.Rows, error)
+		QueryRow(query string, args ...interface{}) *sql.Row
+		QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+		Exec(query string, args ...interface{}) (sql.Result, error)
+		Select(dest interface{}, query string, args ...interface{}) error
+		Rebind(query string) string
+		Beginx() (*sqlx.Tx, error)
+		BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+		MustBegin() *sqlx.Tx
+		PingContext(ctx context.Context) error
+	}
+
 	// Seeder struct.
 	Seeder struct {
 		*Worker
-		DB     *sqlx.DB
-		schema string
-		dbName string
-		seeds  []*Seed
+		DB DBConn
+		// Force re-runs seeds even if already applied.
+		Force bool
+		// Transactional runs all seeds in a single transaction,
+		// none of them is applied if any fails.
+		// By default each seed is committed in its own transaction.
+		Transactional bool
+		// StrictChecksum makes seeding fail instead of
+		// logging a warning when an already applied seed
+		// has changed since it was applied.
+		StrictChecksum bool
+		// ContinueOnError attempts every seed even if some of them fail,
+		// each failed seed only reverts its own transaction.
+		// Seeds depending on a failed one are skipped.
+		// Seeding returns all failures combined.
+		// It is ignored in transactional mode.
+		ContinueOnError bool
+		// Now returns the time stored as seed application time,
+		// defaults to time.Now.
+		Now func() time.Time
+		// IDGen returns the ID of new seed records,
+		// it must be a valid UUID string.
+		// Defaults to a random (v4) UUID generator.
+		IDGen func() string
+		// Hooks
+		// BeforeAll and AfterAll run in their own transaction
+		// before the first and after the last seed.
+		// BeforeEach and AfterEach run in the seed transaction,
+		// nil for non transactional seeds,
+		// an error returned by a before hook aborts seeding.
+		BeforeAll  func(tx *sqlx.Tx) error
+		AfterAll   func(tx *sqlx.Tx) error
+		BeforeEach func(name string, tx *sqlx.Tx) error
+		AfterEach  func(name string, tx *sqlx.Tx, err error)
+		// OnCreateDB is called before setup creates a missing database,
+		// an error returned by it aborts creation.
+		OnCreateDB func(name string) error
+		// TablePrefix is prepended to seeder table name so that
+		// seeds can be tracked separately, i.e.: per tenant.
+		TablePrefix string
+		// Progress, if set, is notified of seeding advance.
+		Progress Progress
+		// StatementHook, if set, is called after each statement
+		// run by package seed executors, i.e.: file and table seeds.
+		StatementHook StatementHook
+		dialect       dialect
+		// conn is the connection opened by seeder, if any,
+		// an injected DB is never closed by seeder.
+		conn *sqlx.DB
+		// admin is the connection to admin database opened
+		// by seeder, if any, to create and drop seeder database.
+		admin *sqlx.DB
+		// sharedConn and sharedAdmin are true for clones while
+		// conn and admin are the ones of the original seeder,
+		// they are owned by it and never closed by the clone.
+		sharedConn, sharedAdmin bool
+		// migrator, if set, must have no pending migrations before seeding.
+		migrator *Migrator
+		// parallelism overrides 'seed.parallelism' if greater than zero.
+		parallelism int
+		schema      string
+		dbName      string
+		table       string
+		seeds       []*Seed
+		// stats of last seeding run.
+		stats   Stats
+		statsMu sync.Mutex
 	}
 
 	// Exec interface.
 	SeedExec interface {
-		Config(seed SeedFx)
-		GetSeed() (up SeedFx)
+		Config(seed SeedFx, unseed SeedFx)
+		GetSeed() (seed SeedFx)
+		GetUnseed() (unseed SeedFx)
 		SetTx(tx *sqlx.Tx)
 		GetTx() (tx *sqlx.Tx)
+		SetCtx(ctx context.Context)
+	}
+
+	// SeedDependent can be optionally implemented
+	// by a SeedExec to declare the name of the seeds
+	// that must be applied before it.
+	// Seeds that declare their dependencies are applied concurrently
+	// by dependency level up to 'seed.parallelism' at a time,
+	// except in transactional mode, so BeforeEach and AfterEach
+	// hooks should be safe for concurrent use.
+	SeedDependent interface {
+		DependsOn() []string
+	}
+
+	// SeedRecordNamer can be optionally implemented
+	// by a SeedExec to set the name and fx stored
+	// in seeder table instead of the ones
+	// derived from its seed function.
+	SeedRecordNamer interface {
+		RecordName() string
+	}
+
+	// SeedVersioner can be optionally implemented
+	// by a SeedExec to identify its current version.
+	// Its checksum is stored when the seed is applied
+	// so that later changes to it can be detected.
+	SeedVersioner interface {
+		Version() string
+	}
+
+	// SeedReapplier can be optionally implemented
+	// by a SeedVersioner that can be safely run again
+	// to be reapplied when its checksum changes,
+	// instead of reporting the change, i.e.: to insert rows
+	// added to it since it was applied.
+	SeedReapplier interface {
+		ReapplyOnChange() bool
+	}
+
+	// SeedIsolator can be optionally implemented
+	// by a SeedExec to set the isolation level
+	// of its transaction, driver default is used otherwise.
+	// It is ignored in transactional mode.
+	SeedIsolator interface {
+		IsolationLevel() sql.IsolationLevel
+	}
+
+	// SeedVerifier can be optionally implemented
+	// by a SeedExec to check that its data is present.
+	// tx is read-only and always reverted.
+	SeedVerifier interface {
+		Verify(tx *sqlx.Tx) error
+	}
+
+	// SeedConfigurer can be optionally implemented
+	// by a SeedExec to receive seeder config
+	// before running.
+	SeedConfigurer interface {
+		SetCfg(cfg *Config)
+	}
+
+	// SeedLogger can be optionally implemented
+	// by a SeedExec to receive seeder logger
+	// before running.
+	SeedLogger interface {
+		SetLog(log Logger)
+	}
+
+	// StatementHook receives a statement run by a seed executor,
+	// its arguments, duration and resulting error.
+	StatementHook func(sql string, args []interface{}, d time.Duration, err error)
+
+	// statementHooker is implemented by package executors
+	// to report the statements they run.
+	statementHooker interface {
+		setStatementHook(h StatementHook)
+	}
+
+	// dialectUser is implemented by package executors
+	// that build their own statements, i.e.: TableSeedExec,
+	// so that they quote identifiers as seeder dialect does.
+	dialectUser interface {
+		setDialect(d dialect)
+	}
+
+	// SeedRowCounter can be optionally implemented
+	// by a SeedExec to report the number of rows
+	// affected by its last execution.
+	SeedRowCounter interface {
+		RowsAffected() int64
+	}
+
+	// SeedTagger can be optionally implemented
+	// by a SeedExec to declare the tags
+	// used to select it in a tagged run.
+	SeedTagger interface {
+		Tags() []string
+	}
+
+	// SeedSchemer can be optionally implemented
+	// by a SeedExec to run it with its search path
+	// set to the returned schema.
+	// Seeder table is not affected.
+	SeedSchemer interface {
+		Schema() string
+	}
+
+	// SeedTimeouter can be optionally implemented
+	// by a SeedExec to override 'seed.timeout'
+	// config value, zero disables the timeout.
+	SeedTimeouter interface {
+		Timeout() time.Duration
+	}
+
+	// SeedEnvironmenter can be optionally implemented
+	// by a SeedExec to restrict the 'app.env' environments
+	// where it can be applied, i.e.: 'dev', 'test'.
+	// Seeds that don't implement it are applied in any environment.
+	SeedEnvironmenter interface {
+		Environments() []string
+	}
+
+	// SeedNonTransactional can be optionally implemented
+	// by a SeedExec that must run outside a transaction,
+	// i.e.: 'CREATE INDEX CONCURRENTLY'.
+	// Its transaction is nil, it should use the database
+	// received through SeedDBSetter, and each statement is
+	// committed as soon as it is run. Search path and statement
+	// timeout are not set. It is then recorded as applied
+	// in its own transaction.
+	// Such seeds cannot run in transactional mode nor in a caller transaction.
+	SeedNonTransactional interface {
+		NonTransactional() bool
+	}
+
+	// SeedDBSetter can be optionally implemented
+	// by a SeedExec to receive seeder database before running.
+	SeedDBSetter interface {
+		SetDB(db DBConn)
+	}
+
+	// SeedPhaser can be optionally implemented by a SeedExec
+	// to declare its phase, PhaseStructure or PhaseData.
+	// Structure seeds, i.e.: lookup tables, run before data ones
+	// regardless of registration order, seeds without phase are data seeds.
+	// Dependencies still come first, a structure seed that
+	// depends on a data seed runs after it.
+	SeedPhaser interface {
+		Phase() string
+	}
+
+	// SeedPrioritizer can be optionally implemented
+	// by a SeedExec to be run before seeds with
+	// a higher priority, default priority is zero.
+	// Dependencies always come first, priority only
+	// orders seeds whose dependencies are satisfied,
+	// seeds applied concurrently within a dependency level
+	// are only started in priority order.
+	SeedPrioritizer interface {
+		Priority() int
 	}
 
 	// Seed struct.
@@ -43,283 +298,2713 @@ type (
 		Executor SeedExec
 	}
 
-	seedRecord struct {
-		ID        uuid.UUID `db:"id" json:"id"`
+	// SeedResult is the outcome of a seed in a seeding run.
+	// Applied is false for seeds skipped because
+	// they were already applied.
+	// Skipped is true for seeds not attempted because
+	// a seed they depend on failed, see ContinueOnError.
+	// RowsAffected is only reported by executors
+	// implementing SeedRowCounter, zero otherwise.
+	SeedResult struct {
+		Name         string
+		Duration     time.Duration
+		RowsAffected int64
+		Applied      bool
+		Skipped      bool
+		Err          error
+	}
+
+	// SetupResult reports what seeder setup did.
+	SetupResult struct {
+		DBName       string
+		DBCreated    bool
+		Table        string
+		TableCreated bool
+	}
+
+	// Stats are the counters of a seeding run.
+	Stats struct {
+		// Applied seeds.
+		Applied int
+		// Skipped seeds, already applied
+		// or depending on a failed one.
+		Skipped int
+		// Failed seeds.
+		Failed int
+		// Committed transactions.
+		Committed int
+		// RolledBack transactions.
+		RolledBack int
+	}
+
+	// AppliedSeed is a seed recorded as applied in seeder table.
+	AppliedSeed struct {
 		Name      string    `db:"name" json:"name"`
 		Fx        string    `db:"fx" json:"fx"`
-		IsApplied bool      `db:"is_applied" json:"isApplied"`
-		CreatedAt time.Time `db:"created_at" json:"createdAt"`
+		AppliedAt time.Time `db:"created_at" json:"appliedAt"`
+	}
+
+	seedRecord struct {
+		ID        string         `db:"id" json:"id"`
+		Name      string         `db:"name" json:"name"`
+		Fx        string         `db:"fx" json:"fx"`
+		Checksum  sql.NullString `db:"checksum" json:"checksum"`
+		Schema    sql.NullString `db:"schema_name" json:"schema"`
+		Tags      sql.NullString `db:"tags" json:"tags"`
+		IsApplied bool           `db:"is_applied" json:"isApplied"`
+		CreatedAt time.Time      `db:"created_at" json:"createdAt"`
 	}
 )
 
 const (
-	pgSeederTable = "seeds"
+	defSeederTable = "seeds"
+)
 
-	pgCreateSeederSt = `CREATE TABLE %s.%s (
-		id UUID PRIMARY KEY,
-		name VARCHAR(64),
-		fx VARCHAR(64),
- 		is_applied BOOLEAN,
-		created_at TIMESTAMP
-	);`
+// NewSeeder.
+// Database engine is selected using 'db.engine' config value
+// (postgres, mysql or sqlite), defaults to postgres.
+// Seeder log verbosity is set using 'seed.logLevel' config value
+// (quiet, info or debug), by default it is left to log.
+// Config is validated on creation if 'seed.validate' is true,
+// errors are logged, use Validate to get them.
+// If db is not nil it is used as is to seed and it is
+// assumed to be connected to seeder database, so its
+// existence is not checked; database and schema config
+// values are still used to qualify seeder objects.
+// Otherwise a connection to seeder database is opened
+// from config on first use.
+// A connection to admin database is always opened from config
+// when seeder database has to be checked, created or dropped.
+// Options are applied in order after config values.
+func NewSeeder(cfg *Config, log Logger, name string, db *sqlx.DB, opts ...SeederOption) *Seeder {
+	d := newDialect(cfg.ValOrDef("db.engine", pgEngine))
 
-	pgDropSeederSt = `DROP TABLE %s.%s;`
+	m := &Seeder{
+		Worker:  NewWorker(cfg, log, name),
+		Now:     time.Now,
+		IDGen:   genUUID,
+		dialect: d,
+		schema:  d.Schema(cfg),
+		dbName:  d.DBName(cfg),
+		table:   cfg.ValOrDef("seed.table", defSeederTable),
+	}
 
-	pgSelSeederSt = `SELECT is_applied FROM %s.%s WHERE name = '%s' and is_applied = true;`
+	m.Log = newLevelLogger(m.Log, cfg.ValOrDef("seed.logLevel", ""))
 
-	pgRecSeederSt = `INSERT INTO %s.%s (id, name, fx, is_applied, created_at)
-		VALUES (:id, :name, :fx, :is_applied, :created_at);`
+	// Avoid storing a nil *sqlx.DB as a non nil DBConn.
+	if db != nil {
+		m.DB = db
+	}
 
-	pgDelSeederSt = `DELETE FROM %s.%s WHERE name = '%s' and is_applied = true;`
-)
+	for _, opt := range opts {
+		opt(m)
+	}
 
-// NewSeeder.
-func NewSeeder(cfg *Config, log Logger, name string, db *sqlx.DB) *Seeder {
-	m := &Seeder{
-		Worker: NewWorker(cfg, log, name),
-		DB:     db,
-		schema: cfg.ValOrDef("pg.schema", ""),
-		dbName: cfg.ValOrDef("pg.database", ""),
+	if cfg.ValAsBool("seed.validate", false) {
+		if err := m.Validate(); err != nil {
+			m.Log.Error(err, "Invalid seeder config")
+		}
 	}
 
 	return m
 }
 
-// pgConnect to postgre database
-// mainly user to create and drop app database.
-func (s *Seeder) pgConnect() error {
-	db, err := sqlx.Open("postgres", s.pgDbURL())
+// NewSeederFromSQL is like NewSeeder but uses a database/sql connection,
+// it is wrapped using the driver of 'db.engine' config value.
+// db is not closed by seeder.
+func NewSeederFromSQL(cfg *Config, log Logger, name string, db *sql.DB, opts ...SeederOption) *Seeder {
+	var xdb *sqlx.DB
+	if db != nil {
+		d := newDialect(cfg.ValOrDef("db.engine", pgEngine))
+		xdb = sqlx.NewDb(db, d.DriverName())
+	}
+
+	return NewSeeder(cfg, log, name, xdb, opts...)
+}
+
+// Clone returns a copy of the seeder that shares its database
+// pools, config, logger and hooks but has its own stats
+// and mutable state, so that it can be used
+// from a different goroutine, i.e.: per test.
+// Seeds are not copied, executors hold the transaction
+// and context of the run using them, so the clone
+// only has execs, which must not be used by other seeders,
+// i.e.: new ones built the same way as the original ones.
+// A clone made without execs has no seeds.
+// The clone never closes the pools, they are still
+// owned by the original seeder.
+// Pools are only shared if already open, a clone of a seeder
+// that has not connected yet opens its own ones on first use;
+// call Start before cloning to share them.
+func (s *Seeder) Clone(execs ...SeedExec) *Seeder {
+	w := *s.Worker
+
+	c := &Seeder{
+		Worker:          &w,
+		DB:              s.DB,
+		conn:            s.conn,
+		admin:           s.admin,
+		sharedConn:      s.conn != nil,
+		sharedAdmin:     s.admin != nil,
+		Force:           s.Force,
+		Transactional:   s.Transactional,
+		StrictChecksum:  s.StrictChecksum,
+		ContinueOnError: s.ContinueOnError,
+		Now:             s.Now,
+		IDGen:           s.IDGen,
+		BeforeAll:       s.BeforeAll,
+		AfterAll:        s.AfterAll,
+		BeforeEach:      s.BeforeEach,
+		AfterEach:       s.AfterEach,
+		OnCreateDB:      s.OnCreateDB,
+		TablePrefix:     s.TablePrefix,
+		Progress:        s.Progress,
+		StatementHook:   s.StatementHook,
+		dialect:         s.dialect,
+		migrator:        s.migrator,
+		parallelism:     s.parallelism,
+		schema:          s.schema,
+		dbName:          s.dbName,
+		table:           s.table,
+	}
+
+	c.RegisterSeeds(execs...)
+
+	return c
+}
+
+// Validate checks that required config values
+// are present, schema name is a valid identifier
+// and registered seeds have unique names.
+// All problems found are reported in the returned error.
+func (s *Seeder) Validate() error {
+	var problems []string
+
+	// Connection keys are not required if 'db.url' is set.
+	if s.Cfg.ValOrDef("db.url", "") == "" {
+		for _, k := range s.dialect.RequiredKeys() {
+			if strings.TrimSpace(s.Cfg.ValOrDef(k, "")) == "" {
+				problems = append(problems, fmt.Sprintf("'%s' is required", k))
+			}
+		}
+	} else if err := s.dialect.CheckDSN(s.Cfg); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if !identRegex.MatchString(s.schema) {
+		problems = append(problems, fmt.Sprintf("schema '%s' is not a valid identifier", s.schema))
+	}
+
+	for _, name := range s.duplicateSeeds() {
+		problems = append(problems, fmt.Sprintf("seed name '%s' is registered more than once", name))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid seeder config: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// pgConnect opens a connection to dsn.
+// Connection is retried with exponential backoff
+// up to 'db.connectRetries' times starting at 'db.connectBackoff'
+// or until context is done.
+func (s *Seeder) pgConnect(ctx context.Context, dsn string) (*sqlx.DB, error) {
+	retries := int(s.Cfg.ValAsInt("db.connectRetries", 5))
+
+	ib, err := time.ParseDuration(s.Cfg.ValOrDef("db.connectBackoff", "500ms"))
 	if err != nil {
-		s.Log.Error(err, "Connection error")
-		return err
+		s.Log.Error(err, "Invalid connection backoff")
+		ib = 500 * time.Millisecond
+	}
+
+	notify := func(err error, next time.Duration) {
+		s.Log.Info("Connection failed", "error", err.Error(), "retrying-in", next.String())
 	}
 
-	err = db.Ping()
+	db, err := waitForDB(ctx, s.dialect.DriverName(), dsn, retries, ib, s.setPool, notify)
 	if err != nil {
 		s.Log.Error(err, "Connection error")
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// setPool configures db connection pool using
+// 'db.maxOpenConns', 'db.maxIdleConns' and 'db.connMaxLifetime'
+// config values.
+func (s *Seeder) setPool(db *sqlx.DB) {
+	db.SetMaxOpenConns(int(s.Cfg.ValAsInt("db.maxOpenConns", 10)))
+	db.SetMaxIdleConns(int(s.Cfg.ValAsInt("db.maxIdleConns", 5)))
+
+	lt, err := time.ParseDuration(s.Cfg.ValOrDef("db.connMaxLifetime", "5m"))
+	if err != nil {
+		s.Log.Error(err, "Invalid connection max lifetime")
+		return
+	}
+
+	db.SetConnMaxLifetime(lt)
+}
+
+// connect opens a connection to seeder database using
+// config values only if no database was provided on creation.
+// Seeder table and seeds are always run through it.
+func (s *Seeder) connect(ctx context.Context) error {
+	if s.DB != nil {
+		return nil
+	}
+
+	db, err := s.pgConnect(ctx, s.dbURL())
+	if err != nil {
 		return err
 	}
 
 	s.DB = db
+	s.conn = db
 	return nil
 }
 
+// injected tells if seeder database
+// was provided on creation.
+func (s *Seeder) injected() bool {
+	return s.DB != nil && s.conn == nil
+}
+
+// adminConn returns the connection used to check, create
+// and drop seeder database, it doesn't need to exist.
+// It is opened to admin database from config on first use,
+// even if a database was provided on creation.
+func (s *Seeder) adminConn(ctx context.Context) (*sqlx.DB, error) {
+	if s.admin != nil {
+		return s.admin, nil
+	}
+
+	err := s.dialect.CheckDSN(s.Cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := s.pgConnect(ctx, s.pgDbURL())
+	if err != nil {
+		return nil, err
+	}
+
+	s.admin = db
+	return db, nil
+}
+
+// closeConn closes the connection to seeder database
+// opened by seeder, if any, so that it doesn't prevent
+// dropping it, it is opened again on next use.
+func (s *Seeder) closeConn() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	var err error
+	if !s.sharedConn {
+		err = s.conn.Close()
+	}
+	s.conn = nil
+	s.DB = nil
+	s.sharedConn = false
+	return err
+}
+
+// Start opens seeder connection using config values
+// if no database was provided on creation.
+func (s *Seeder) Start() error {
+	return s.connect(context.Background())
+}
+
+// Stop closes the connection opened by seeder, if any.
+// A database provided on creation is left open.
+func (s *Seeder) Stop() error {
+	return s.Close()
+}
+
+// Close closes the connections opened by seeder, if any.
+// A database provided on creation is left open,
+// closing it is up to its owner.
+// Callers should 'defer seeder.Close()' once the seeder is created.
+func (s *Seeder) Close() error {
+	err := s.closeConn()
+
+	if s.admin != nil {
+		if !s.sharedAdmin {
+			err = errors.Join(err, s.admin.Close())
+		}
+		s.admin = nil
+		s.sharedAdmin = false
+	}
+
+	return err
+}
+
 // GetTx returns a new transaction from seeder connection.
+// It panics if the transaction cannot be started.
+//
+// Deprecated: use BeginTx.
 func (s *Seeder) GetTx() *sqlx.Tx {
 	return s.DB.MustBegin()
 }
 
+// BeginTx returns a new transaction from seeder connection.
+func (s *Seeder) BeginTx() (*sqlx.Tx, error) {
+	return s.DB.Beginx()
+}
+
+// Health checks that seeder database can be reached
+// and its schema exists, nothing is created.
+// Checks are bounded by 'seed.healthTimeout' config value.
+func (s *Seeder) Health(ctx context.Context) error {
+	to, err := time.ParseDuration(s.Cfg.ValOrDef("seed.healthTimeout", "5s"))
+	if err != nil {
+		s.Log.Error(err, "Invalid health timeout")
+		to = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, to)
+	defer cancel()
+
+	err = s.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot connect to database: %w", err)
+	}
+
+	err = s.DB.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot reach database: %w", err)
+	}
+
+	st, args := s.dialect.SchemaExistsSt(s.schema)
+
+	var exists sql.NullBool
+	err = s.DB.QueryRowContext(ctx, s.DB.Rebind(st), args...).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("cannot check schema: %w", err)
+	}
+
+	if !exists.Bool {
+		return fmt.Errorf("schema '%s' does not exist", s.schema)
+	}
+
+	return nil
+}
+
+// RequireMigrations makes seeding fail
+// while m has pending migrations.
+func (s *Seeder) RequireMigrations(m *Migrator) {
+	s.migrator = m
+}
+
+// checkMigrations returns an error listing pending
+// migrations of required migrator, if any.
+func (s *Seeder) checkMigrations() error {
+	if s.migrator == nil {
+		return nil
+	}
+
+	pending, err := s.migrator.PendingMigrations()
+	if err != nil {
+		return fmt.Errorf("cannot check migrations: %w", err)
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("database not migrated, pending migrations: %s", strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
 // PreSetup creates database
 // and seeder table if needed.
-func (s *Seeder) PreSetup() {
-	if !s.dbExists() {
-		s.CreateDb()
+func (s *Seeder) PreSetup() (SetupResult, error) {
+	return s.preSetup(context.Background())
+}
+
+func (s *Seeder) preSetup(ctx context.Context) (res SetupResult, err error) {
+	res = SetupResult{DBName: s.dbName, Table: s.tableName()}
+
+	for _, id := range []string{s.dbName, s.schema, s.tableName()} {
+		if err := validIdent(id); err != nil {
+			return res, err
+		}
+	}
+
+	exists, err := s.seedDBExists(ctx)
+	if err != nil {
+		return res, fmt.Errorf("cannot check database: %w", err)
+	}
+
+	if !exists {
+		if s.OnCreateDB != nil {
+			err = s.OnCreateDB(s.dbName)
+			if err != nil {
+				return res, fmt.Errorf("%w: '%s', creation aborted: %w", ErrDBNotExist, s.dbName, err)
+			}
+		}
+
+		s.Log.Info("Creating database", "name", s.dbName)
+
+		res.DBName, err = s.CreateDb()
+		if err != nil {
+			return res, fmt.Errorf("cannot create database: %w", err)
+		}
+		res.DBCreated = true
+	}
+
+	err = s.connect(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	// Table creation is idempotent, existence is only
+	// checked to report if it was created.
+	exists, err = s.seedTableExists()
+	if err != nil {
+		return res, fmt.Errorf("cannot check seeder table: %w", err)
+	}
+
+	res.Table, err = s.createSeederTable()
+	if err != nil {
+		return res, fmt.Errorf("cannot create seeder table: %w", err)
+	}
+	res.TableCreated = !exists
+
+	return res, nil
+}
+
+// setup prepares seeding, creating database and
+// seeder table if needed.
+// If 'seed.autoSetup' is false nothing is created
+// and seeder table must already exist,
+// ErrSeedTableMissing is returned otherwise.
+// Admin database is not used then, only seeder
+// database connection is required.
+func (s *Seeder) setup(ctx context.Context) error {
+	if s.Cfg.ValAsBool("seed.autoSetup", true) {
+		_, err := s.preSetup(ctx)
+		return err
+	}
+
+	err := s.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot connect to database '%s': %w", s.dbName, err)
 	}
 
-	if !s.seedTableExists() {
-		s.createSeederTable()
+	exists, err := s.seedTableExists()
+	if err != nil {
+		return fmt.Errorf("cannot check seeder table: %w", err)
+	}
+
+	if !exists {
+		return fmt.Errorf("%w: '%s.%s', run PreSetup or enable 'seed.autoSetup'", ErrSeedTableMissing, s.schema, s.tableName())
 	}
+
+	return nil
 }
 
 // dbExists returns true if seeder
 // referenced database has been already created.
-// Only for postgress at the moment.
-func (s *Seeder) dbExists() bool {
-	st := fmt.Sprintf(`SELECT EXISTS(
-		SELECT datname FROM pg_catalog.pg_database WHERE lower(datname) = lower('%s'));`, s.dbName)
+func (s *Seeder) dbExists(ctx context.Context) (bool, error) {
+	db, err := s.adminConn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	st, args := s.dialect.DBExistsSt(s.dbName)
 
-	r, err := s.DB.Query(st)
+	r, err := db.Query(db.Rebind(st), args...)
 	if err != nil {
 		s.Log.Error(err, "Error checking database")
-		return false
+		return false, err
 	}
+	defer r.Close()
 
 	for r.Next() {
 		var exists sql.NullBool
 		err = r.Scan(&exists)
 		if err != nil {
 			s.Log.Error(err, "Cannot read query result")
-			return false
+			return false, err
 		}
-		return exists.Bool
+		return exists.Bool, nil
 	}
-	return false
+
+	return false, r.Err()
+}
+
+// seedDBExists is like dbExists but a database provided
+// on creation is assumed to be seeder one without checking,
+// so that admin database is not required to seed it.
+func (s *Seeder) seedDBExists(ctx context.Context) (bool, error) {
+	if s.injected() {
+		return true, nil
+	}
+
+	return s.dbExists(ctx)
 }
 
 // seedExists returns true if seeder table exists.
-func (s *Seeder) seedTableExists() bool {
-	st := fmt.Sprintf(`SELECT EXISTS (
-		SELECT 1
-   	FROM   pg_catalog.pg_class c
-   	JOIN   pg_catalog.pg_namespace n ON n.oid = c.relnamespace
-   	WHERE  n.nspname = '%s'
-   	AND    c.relname = '%s'
-   	AND    c.relkind = 'r'
-	);`, s.schema, s.dbName)
-
-	r, err := s.DB.Query(st)
+func (s *Seeder) seedTableExists() (bool, error) {
+	st, args := s.dialect.TableExistsSt(s.schema, s.tableName())
+
+	r, err := s.DB.Query(s.DB.Rebind(st), args...)
 	if err != nil {
 		s.Log.Error(err, "Error checking database")
-		return false
+		return false, err
 	}
+	defer r.Close()
 
 	for r.Next() {
 		var exists sql.NullBool
 		err = r.Scan(&exists)
 		if err != nil {
 			s.Log.Error(err, "Cannot read query result")
-			return false
+			return false, err
 		}
 
-		return exists.Bool
+		return exists.Bool, nil
 	}
-	return false
-}
 
-// CreateDb for seeder.
-func (s *Seeder) CreateDb() (string, error) {
-	//s.CloseAppConns()
-	st := fmt.Sprintf(pgCreateDbSt, s.dbName)
+	return false, r.Err()
+}
 
-	_, err := s.DB.Exec(st)
+// seedTableReady returns true if seeder database
+// and table exist, nothing is created.
+// Seeder database is only connected if it exists.
+func (s *Seeder) seedTableReady(ctx context.Context) (bool, error) {
+	exists, err := s.seedDBExists(ctx)
 	if err != nil {
-		return s.dbName, err
+		return false, fmt.Errorf("cannot check database: %w", err)
 	}
 
-	return s.dbName, nil
-}
-
-func (s *Seeder) createSeederTable() (string, error) {
-	tx := s.GetTx()
+	if !exists {
+		return false, nil
+	}
 
-	st := fmt.Sprintf(pgCreateSeederSt, s.schema, pgSeederTable)
+	err = s.connect(ctx)
+	if err != nil {
+		return false, err
+	}
 
-	_, err := tx.Exec(st)
+	exists, err = s.seedTableExists()
 	if err != nil {
-		return pgSeederTable, err
+		return false, fmt.Errorf("cannot check seeder table: %w", err)
 	}
 
-	return pgSeederTable, tx.Commit()
+	return exists, nil
 }
 
-func (s *Seeder) AddSeed(e SeedExec) {
-	s.seeds = append(s.seeds, &Seed{Executor: e})
+// tableName returns seeder table name including its prefix.
+func (s *Seeder) tableName() string {
+	return s.TablePrefix + s.table
 }
 
-func (s *Seeder) Seed() error {
-	s.PreSetup()
-
-	for _, sd := range s.seeds {
-		exec := sd.Executor
-		fn := getFxName(exec.GetSeed())
-		name := seedName(fn)
-
-		// Continue if already applied
-		if !s.canApplySeed(name) {
-			s.Log.Info("Seed already applied", "name", name)
-			continue
-		}
+// tableSt formats st with seeder schema and table names.
+func (s *Seeder) tableSt(st string) string {
+	return fmt.Sprintf(st, s.dialect.QuoteIdent(s.schema), s.dialect.QuoteIdent(s.tableName()))
+}
 
-		// Get a new Tx from seeder
-		tx := s.GetTx()
-		// Pass Tx to the executor
-		exec.SetTx(tx)
-
-		// Execute seed
-		values := reflect.ValueOf(exec).MethodByName(fn).Call([]reflect.Value{})
-
-		// Read error
-		err, ok := values[0].Interface().(error)
-		if !ok && err != nil {
-			fmt.Printf("Seed step not executed: %s\n", fn) // TODO: Remove log
-			fmt.Printf("Err  %+v' of type %T\n", err, err) // TODO: Remove log.
-			msg := fmt.Sprintf("cannot run seeding '%s': %s", fn, err.Error())
-			tx.Rollback()
-			return errors.New(msg)
-		}
+// CreateDb for seeder.
+// It is run through admin database connection.
+func (s *Seeder) CreateDb() (string, error) {
+	db, err := s.adminConn(context.Background())
+	if err != nil {
+		return s.dbName, err
+	}
 
-		// Register seed
-		err = s.recSeed(exec)
+	err = s.CloseAppConns()
+	if err != nil {
+		return s.dbName, err
+	}
 
-		err = tx.Commit()
-		if err != nil {
-			msg := fmt.Sprintf("Commit error: %s\n", err.Error())
-			fmt.Printf("Commit error: %s\n", msg)
-			tx.Rollback()
-			return errors.New(msg)
-		}
+	st := s.dialect.CreateDbSt(s.dbName)
 
-		s.Log.Info("Seed step executed", "name", fn)
+	_, err = db.Exec(st)
+	if err != nil {
+		return s.dbName, err
 	}
 
-	return nil
+	return s.dbName, nil
 }
 
-func (s *Seeder) canApplySeed(name string) bool {
-	st := fmt.Sprintf(pgSelSeederSt, s.schema, pgSeederTable, name)
-	r, err := s.DB.Query(st)
-
+// DropDb for seeder.
+// It is run through admin database connection,
+// seeder connection to the dropped database, if any, is closed.
+func (s *Seeder) DropDb() (string, error) {
+	db, err := s.adminConn(context.Background())
 	if err != nil {
-		s.Log.Error(err, "Cannot determine seeder status")
-		return false
+		return s.dbName, err
 	}
 
+	err = s.closeConn()
+	if err != nil {
+		s.Log.Info("Close connection error", "error", err.Error())
+	}
+
+	err = s.CloseAppConns()
+	if err != nil {
+		return s.dbName, err
+	}
+
+	st := s.dialect.DropDbSt(s.dbName)
+
+	_, err = db.Exec(st)
+	if err != nil {
+		return s.dbName, err
+	}
+
+	return s.dbName, nil
+}
+
+// Reset drops and recreates seeder database and table.
+// It requires 'seed.allowReset' config value to be true.
+func (s *Seeder) Reset() error {
+	if !s.Cfg.ValAsBool("seed.allowReset", false) {
+		return errors.New("seeder reset not allowed: set 'seed.allowReset' to enable it")
+	}
+
+	_, err := s.DropDb()
+	if err != nil {
+		s.Log.Info("Drop database error", "error", err.Error())
+		// Don't return maybe it was not created before.
+	}
+
+	_, err = s.CreateDb()
+	if err != nil {
+		return fmt.Errorf("cannot create database: %w", err)
+	}
+
+	err = s.connect(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = s.createSeederTable()
+	if err != nil {
+		return fmt.Errorf("cannot create seeder table: %w", err)
+	}
+
+	return nil
+}
+
+// ResetSchema drops and recreates seeder schema and table.
+// Other objects in the schema are also dropped,
+// database is kept.
+// It requires 'seed.allowSchemaReset' config value to be true.
+func (s *Seeder) ResetSchema() error {
+	if !s.Cfg.ValAsBool("seed.allowSchemaReset", false) {
+		return errors.New("seeder schema reset not allowed: set 'seed.allowSchemaReset' to enable it")
+	}
+
+	st := s.dialect.ResetSchemaSt(s.schema)
+	if st == "" {
+		return fmt.Errorf("schema reset not supported by '%s' engine", s.dialect.DriverName())
+	}
+
+	err := s.connect(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.Exec(st)
+	if err != nil {
+		return fmt.Errorf("cannot reset schema '%s': %w", s.schema, err)
+	}
+
+	_, err = s.createSeederTable()
+	if err != nil {
+		return fmt.Errorf("cannot create seeder table: %w", err)
+	}
+
+	return nil
+}
+
+// CreateTemplate applies pending seeds to seeder database
+// so that it can be used as template by CloneFromTemplate.
+// Seeder connection to it is closed afterwards, a database
+// provided on creation should then be closed by its owner.
+// It requires 'seed.allowTemplate' config value to be true.
+func (s *Seeder) CreateTemplate() error {
+	if !s.Cfg.ValAsBool("seed.allowTemplate", false) {
+		return errors.New("seeder template not allowed: set 'seed.allowTemplate' to enable it")
+	}
+
+	err := s.Seed()
+	if err != nil {
+		return fmt.Errorf("cannot seed template: %w", err)
+	}
+
+	err = s.closeConn()
+	if err != nil {
+		return fmt.Errorf("cannot close template connection: %w", err)
+	}
+
+	return nil
+}
+
+// CloneFromTemplate creates database name as a copy
+// of seeder database, seeds included, which is faster
+// than seeding a new one, i.e.: between test suites.
+// Postgres requires that no other session is connected
+// to the template so they are terminated using CloseAppConns
+// and the copy is made through a connection to admin database
+// opened from config, even if a database was provided on creation.
+// It requires 'seed.allowTemplate' config value to be true.
+func (s *Seeder) CloneFromTemplate(name string) error {
+	if !s.Cfg.ValAsBool("seed.allowTemplate", false) {
+		return errors.New("seeder template not allowed: set 'seed.allowTemplate' to enable it")
+	}
+
+	st := s.dialect.CloneDbSt(name, s.dbName)
+	if st == "" {
+		return fmt.Errorf("database template not supported by '%s' engine", s.dialect.DriverName())
+	}
+
+	db, err := s.adminConn(context.Background())
+	if err != nil {
+		return err
+	}
+
+	err = s.closeConn()
+	if err != nil {
+		return fmt.Errorf("cannot close template connection: %w", err)
+	}
+
+	err = s.closeAppConns(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(st)
+	if err != nil {
+		return fmt.Errorf("cannot clone database '%s' into '%s': %w", s.dbName, name, err)
+	}
+
+	return nil
+}
+
+// CloseAppConns terminates other sessions
+// connected to seeder database.
+// It is run through admin database connection
+// and it is a no-op for engines that don't require it.
+func (s *Seeder) CloseAppConns() error {
+	db, err := s.adminConn(context.Background())
+	if err != nil {
+		return err
+	}
+
+	return s.closeAppConns(db)
+}
+
+// closeAppConns terminates other sessions
+// connected to seeder database using db.
+func (s *Seeder) closeAppConns(db DBConn) error {
+	st := s.dialect.CloseConnsSt()
+	if st == "" {
+		return nil
+	}
+
+	_, err := db.Exec(db.Rebind(st), s.dbName)
+	if err != nil {
+		return fmt.Errorf("cannot close database connections: %w", err)
+	}
+
+	return nil
+}
+
+// createSeederTable creates seeder schema, if required,
+// and table unless they already exist.
+// It is safe to run concurrently from different processes.
+func (s *Seeder) createSeederTable() (string, error) {
+	tx, err := s.BeginTx()
+	if err != nil {
+		return s.tableName(), fmt.Errorf("cannot start transaction: %w", err)
+	}
+
+	st := s.dialect.CreateSchemaSt(s.schema)
+	if st != "" {
+		_, err = tx.Exec(st)
+		if err != nil {
+			tx.Rollback()
+			return s.tableName(), fmt.Errorf("cannot create schema: %w", err)
+		}
+	}
+
+	err = s.setSeederSchema(context.Background(), tx)
+	if err != nil {
+		tx.Rollback()
+		return s.tableName(), fmt.Errorf("cannot set schema: %w", err)
+	}
+
+	st = s.tableSt(s.dialect.CreateSeederTableSt())
+
+	_, err = tx.Exec(st)
+	if err != nil {
+		tx.Rollback()
+		return s.tableName(), err
+	}
+
+	err = s.ensureSeederTableSchema(tx)
+	if err != nil {
+		tx.Rollback()
+		return s.tableName(), fmt.Errorf("cannot upgrade seeder table: %w", err)
+	}
+
+	return s.tableName(), tx.Commit()
+}
+
+// ensureSeederTableSchema adds the columns introduced
+// after seeder table was first created, if missing,
+// so that tables created by previous versions are upgraded.
+func (s *Seeder) ensureSeederTableSchema(tx *sqlx.Tx) error {
+	for _, st := range s.dialect.UpgradeSeederTableSts() {
+		_, err := tx.Exec(s.tableSt(st))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DropSeederTable drops seeder table.
+// If ifExists is true it doesn't fail when
+// the table does not exist.
+// If cascade is true objects that depend
+// on it are also dropped.
+func (s *Seeder) DropSeederTable(ifExists, cascade bool) error {
+	err := s.connect(context.Background())
+	if err != nil {
+		return err
+	}
+
+	st := s.tableSt(s.dialect.DropSeederSt(ifExists, cascade))
+
+	_, err = s.DB.Exec(st)
+	if err != nil {
+		return fmt.Errorf("cannot drop seeder table: %w", err)
+	}
+
+	return nil
+}
+
+// AddSeed registers a seed executor.
+func (s *Seeder) AddSeed(e SeedExec) {
+	s.seeds = append(s.seeds, &Seed{Executor: e})
+}
+
+// Seed runs all pending seeds.
+func (s *Seeder) Seed() error {
+	return s.SeedContext(context.Background())
+}
+
+// SeedContext runs all pending seeds.
+// Seeding is aborted and in-flight transaction
+// reverted when context is done.
+func (s *Seeder) SeedContext(ctx context.Context) error {
+	_, err := s.SeedReportContext(ctx)
+	return err
+}
+
+// SeedReport runs all pending seeds and
+// returns the outcome of each one of them.
+func (s *Seeder) SeedReport() ([]SeedResult, error) {
+	return s.SeedReportContext(context.Background())
+}
+
+// SeedReportContext runs all pending seeds and
+// returns the outcome of each one of them.
+// Seeding is aborted and in-flight transaction
+// reverted when context is done.
+func (s *Seeder) SeedReportContext(ctx context.Context) ([]SeedResult, error) {
+	return s.seedReport(ctx, nil)
+}
+
+// SeedTagged runs pending seeds tagged with any of tags.
+// All pending seeds are run if no tag is given.
+func (s *Seeder) SeedTagged(tags ...string) error {
+	return s.SeedTaggedContext(context.Background(), tags...)
+}
+
+// SeedTaggedContext runs pending seeds tagged with any of tags.
+// All pending seeds are run if no tag is given.
+func (s *Seeder) SeedTaggedContext(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return s.SeedContext(ctx)
+	}
+
+	_, err := s.seedReport(ctx, func(sd *Seed) bool {
+		return sd.HasTag(tags...)
+	})
+
+	return err
+}
+
+// seedReport runs pending seeds accepted by filter,
+// all of them if filter is nil.
+// Seeds keep their dependency order but
+// dependencies are not run if not accepted.
+func (s *Seeder) seedReport(ctx context.Context, filter func(sd *Seed) bool) ([]SeedResult, error) {
+	s.resetStats()
+	results, err := s.runSeeds(ctx, filter)
+	s.countResults(results)
+	return results, err
+}
+
+// runSeeds runs pending seeds accepted by filter.
+func (s *Seeder) runSeeds(ctx context.Context, filter func(sd *Seed) bool) ([]SeedResult, error) {
+	err := s.checkEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing is created unless the schema is migrated.
+	err = s.checkMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.setup(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("seeding setup failed: %w", err)
+	}
+
+	seeds, err := s.sortSeeds()
+	if err != nil {
+		return nil, err
+	}
+
+	if filter != nil {
+		var sel []*Seed
+		for _, sd := range seeds {
+			if filter(sd) {
+				sel = append(sel, sd)
+			}
+		}
+		seeds = sel
+	}
+
+	seeds, err = s.envSeeds(seeds)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Progress != nil {
+		s.Progress.Start(len(seeds))
+		defer s.Progress.Done()
+	}
+
+	if s.Transactional {
+		return s.seedAll(ctx, seeds)
+	}
+
+	err = s.runHook(ctx, s.BeforeAll)
+	if err != nil {
+		return nil, fmt.Errorf("before all hook failed: %w", err)
+	}
+
+	n := s.parallelism
+	if n <= 0 {
+		n = int(s.Cfg.ValAsInt("seed.parallelism", 4))
+	}
+
+	var results []SeedResult
+	var errs []error
+	// failed seeds, and seeds skipped because of them,
+	// only tracked if ContinueOnError is set.
+	failed := map[string]bool{}
+	for _, grp := range s.seedGroups(seeds, n) {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("seeding aborted: %w", err)
+		}
+
+		var run []*Seed
+		for _, sd := range grp {
+			if dep, ok := failedDep(sd, failed); ok {
+				s.Log.Info("Seed skipped, dependency failed", "name", sd.Name(), "dependency", dep)
+				failed[sd.Name()] = true
+				results = append(results, SeedResult{Name: sd.Name(), Skipped: true})
+				continue
+			}
+			run = append(run, sd)
+		}
+
+		if len(run) == 0 {
+			continue
+		}
+
+		for i, sd := range run {
+			s.step(sd, len(results)+i)
+		}
+
+		res, err := s.applyGroup(ctx, run, n)
+		results = append(results, res...)
+		if err != nil {
+			if !s.ContinueOnError {
+				return results, err
+			}
+
+			for _, r := range res {
+				if r.Err != nil {
+					failed[r.Name] = true
+					errs = append(errs, fmt.Errorf("seed '%s': %w", r.Name, r.Err))
+				}
+			}
+		}
+	}
+
+	err = s.runHook(ctx, s.AfterAll)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("after all hook failed: %w", err))
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// failedDep returns the dependency of sd in failed, if any.
+func failedDep(sd *Seed, failed map[string]bool) (string, bool) {
+	for _, dn := range sd.DependsOn() {
+		if failed[dn] || failed[seedName(dn)] {
+			return dn, true
+		}
+	}
+	return "", false
+}
+
+// env returns current application environment.
+func (s *Seeder) env() string {
+	return s.Cfg.ValOrDef("app.env", "")
+}
+
+// checkEnv refuses seeding in production environment
+// unless 'seed.allowProduction' config value is true.
+func (s *Seeder) checkEnv() error {
+	if strings.EqualFold(s.env(), "production") && !s.Cfg.ValAsBool("seed.allowProduction", false) {
+		return errors.New("seeding not allowed in production: set 'seed.allowProduction' to enable it")
+	}
+	return nil
+}
+
+// envAllowed tells if seed can be applied in current environment.
+func (s *Seeder) envAllowed(sd *Seed) bool {
+	e, ok := sd.Executor.(SeedEnvironmenter)
+	if !ok {
+		return true
+	}
+
+	env := s.env()
+	for _, allowed := range e.Environments() {
+		if strings.EqualFold(allowed, env) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// envSeeds returns the seeds allowed in current environment.
+// The rest are skipped, or make seeding fail if
+// 'seed.envMismatch' config value is 'fail'.
+func (s *Seeder) envSeeds(seeds []*Seed) ([]*Seed, error) {
+	fail := s.Cfg.ValOrDef("seed.envMismatch", "skip") == "fail"
+
+	var sel []*Seed
+	for _, sd := range seeds {
+		if s.envAllowed(sd) {
+			sel = append(sel, sd)
+			continue
+		}
+
+		if fail {
+			return nil, fmt.Errorf("seed '%s' not allowed in '%s' environment", sd.Name(), s.env())
+		}
+
+		s.Log.Info("Seed skipped, not allowed in environment", "name", sd.Name(), "env", s.env())
+	}
+
+	return sel, nil
+}
+
+// step notifies progress, if set,
+// that i-th seed is about to be applied.
+func (s *Seeder) step(sd *Seed, i int) {
+	if s.Progress != nil {
+		s.Progress.Step(sd.Name(), i)
+	}
+}
+
+// SeedInTx runs all registered seeds using tx,
+// no transaction is started, committed or reverted.
+// Seeds are not checked nor recorded in seeder table,
+// all of them are run.
+// Search path and statement timeout of tx are not changed,
+// seeds implementing SeedSchemer are not moved to their schema.
+func (s *Seeder) SeedInTx(tx *sqlx.Tx) error {
+	return s.SeedInTxContext(context.Background(), tx)
+}
+
+// SeedInTxContext runs all registered seeds using tx.
+func (s *Seeder) SeedInTxContext(ctx context.Context, tx *sqlx.Tx) error {
+	err := s.checkEnv()
+	if err != nil {
+		return err
+	}
+
+	seeds, err := s.sortSeeds()
+	if err != nil {
+		return err
+	}
+
+	seeds, err = s.envSeeds(seeds)
+	if err != nil {
+		return err
+	}
+
+	err = requireTx(seeds)
+	if err != nil {
+		return err
+	}
+
+	for _, sd := range seeds {
+		sctx, cancel := s.seedContext(ctx, sd)
+		_, err = s.runSeed(ctx, sctx, tx, sd, false)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seedGroups splits sorted seeds in groups that can be
+// applied concurrently, groups must be applied in order.
+// Only seeds that declare their dependencies are grouped,
+// by dependency level, the rest are applied one at a time.
+// If parallelism is less than 2 all seeds are applied one at a time.
+func (s *Seeder) seedGroups(seeds []*Seed, parallelism int) [][]*Seed {
+	var groups [][]*Seed
+
+	if parallelism < 2 {
+		for _, sd := range seeds {
+			groups = append(groups, []*Seed{sd})
+		}
+		return groups
+	}
+
+	var run []*Seed
+	flush := func() {
+		groups = append(groups, levelGroups(run)...)
+		run = nil
+	}
+
+	for _, sd := range seeds {
+		// Structure and data seeds are never applied concurrently.
+		if len(run) > 0 && phaseRank(run[len(run)-1].Executor) != phaseRank(sd.Executor) {
+			flush()
+		}
+
+		if _, ok := sd.Executor.(SeedDependent); ok {
+			run = append(run, sd)
+			continue
+		}
+
+		flush()
+		groups = append(groups, []*Seed{sd})
+	}
+
+	flush()
+	return groups
+}
+
+// levelGroups groups sorted seeds by dependency level.
+// Dependencies not included in seeds are considered satisfied.
+func levelGroups(seeds []*Seed) [][]*Seed {
+	var groups [][]*Seed
+	levels := make(map[string]int, len(seeds))
+
+	for _, sd := range seeds {
+		lvl := 0
+		for _, dn := range sd.DependsOn() {
+			l, ok := levels[dn]
+			if !ok {
+				l, ok = levels[seedName(dn)]
+			}
+
+			if ok && l+1 > lvl {
+				lvl = l + 1
+			}
+		}
+
+		levels[sd.Name()] = lvl
+
+		for len(groups) <= lvl {
+			groups = append(groups, nil)
+		}
+		groups[lvl] = append(groups[lvl], sd)
+	}
+
+	return groups
+}
+
+// applyGroup applies seeds concurrently, each one
+// in its own transaction, up to parallelism at a time.
+// All seeds are attempted and their errors combined.
+func (s *Seeder) applyGroup(ctx context.Context, seeds []*Seed, parallelism int) ([]SeedResult, error) {
+	if len(seeds) == 1 {
+		res := s.applySeed(ctx, seeds[0])
+		return []SeedResult{res}, res.Err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]SeedResult, len(seeds))
+	sem := make(chan struct{}, parallelism)
+
+	for i, sd := range seeds {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, sd *Seed) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			results[i] = s.applySeed(ctx, sd)
+		}(i, sd)
+	}
+
+	wg.Wait()
+
+	var errs []error
+	for _, res := range results {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// runHook runs hook in its own transaction.
+func (s *Seeder) runHook(ctx context.Context, hook func(tx *sqlx.Tx) error) error {
+	if hook == nil {
+		return nil
+	}
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = s.setSeederSchema(ctx, tx)
+	if err != nil {
+		s.rollback(tx)
+		return err
+	}
+
+	err = hook(tx)
+	if err != nil {
+		s.rollback(tx)
+		return err
+	}
+
+	return s.commit(tx)
+}
+
+// SeedOne runs only the seed identified by name.
+// Name can be either the seed function name or its snake case form.
+func (s *Seeder) SeedOne(name string) error {
+	return s.SeedOneContext(context.Background(), name)
+}
+
+// SeedOneContext runs only the seed identified by name.
+// It returns ErrSeedAlreadyApplied if seed was
+// already applied and Force is not set.
+func (s *Seeder) SeedOneContext(ctx context.Context, name string) error {
+	sd, ok := s.findSeed(name)
+	if !ok {
+		return SeedError{Name: name, Op: "find", Err: ErrSeedNotFound}
+	}
+
+	err := s.checkEnv()
+	if err != nil {
+		return err
+	}
+
+	if !s.envAllowed(sd) {
+		return SeedError{Name: sd.Name(), Op: "apply", Err: fmt.Errorf("not allowed in '%s' environment", s.env())}
+	}
+
+	err = s.checkMigrations()
+	if err != nil {
+		return err
+	}
+
+	err = s.setup(ctx)
+	if err != nil {
+		return fmt.Errorf("seeding setup failed: %w", err)
+	}
+
+	s.resetStats()
+	res := s.applySeed(ctx, sd)
+	s.countResults([]SeedResult{res})
+	if res.Err == nil && !res.Applied {
+		return SeedError{Name: res.Name, Op: "apply", Err: ErrSeedAlreadyApplied}
+	}
+
+	return res.Err
+}
+
+// DryRun returns the name of the seeds that
+// would be applied by Seed, in execution order.
+// Nothing is created nor executed.
+func (s *Seeder) DryRun() ([]string, error) {
+	seeds, err := s.pendingSeeds()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []string{}
+	for _, sd := range seeds {
+		pending = append(pending, sd.Name())
+	}
+
+	return pending, nil
+}
+
+// DryRunJSON writes to w the seeds that would be applied
+// by Seed, in execution order, as a JSON array
+// of objects with their name and fx.
+// Nothing is created nor executed.
+func (s *Seeder) DryRunJSON(w io.Writer) error {
+	seeds, err := s.pendingSeeds()
+	if err != nil {
+		return err
+	}
+
+	type pendingSeed struct {
+		Name string `json:"name"`
+		Fx   string `json:"fx"`
+	}
+
+	pending := []pendingSeed{}
+	for _, sd := range seeds {
+		pending = append(pending, pendingSeed{
+			Name: sd.Name(),
+			Fx:   execFx(sd.Executor),
+		})
+	}
+
+	return json.NewEncoder(w).Encode(pending)
+}
+
+// pendingSeeds returns the seeds that
+// would be applied by Seed, in execution order.
+func (s *Seeder) pendingSeeds() ([]*Seed, error) {
+	seeds, err := s.sortSeeds()
+	if err != nil {
+		return nil, err
+	}
+
+	seeds, err = s.envSeeds(seeds)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked, err := s.seedTableReady(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*Seed
+	for _, sd := range seeds {
+		if tracked && !s.Force {
+			need, err := s.needsApply(sd)
+			if err != nil {
+				return nil, err
+			}
+
+			if !need {
+				continue
+			}
+		}
+
+		pending = append(pending, sd)
+	}
+
+	return pending, nil
+}
+
+// SeedGraph returns the seeds dependency graph
+// in Graphviz DOT format.
+// Edges go from each seed to the seeds depending on it.
+// Seeds recorded as applied are filled in green, the rest in white.
+func (s *Seeder) SeedGraph() (string, error) {
+	seeds, err := s.sortSeeds()
+	if err != nil {
+		return "", err
+	}
+
+	tracked, err := s.seedTableReady(context.Background())
+	if err != nil {
+		return "", err
+	}
+
+	applied := make(map[string]bool, len(seeds))
+	for _, sd := range seeds {
+		if !tracked {
+			break
+		}
+
+		can, err := s.canApplySeed(sd.Name())
+		if err != nil {
+			return "", fmt.Errorf("cannot determine seed '%s' status: %w", sd.Name(), err)
+		}
+		applied[sd.Name()] = !can
+	}
+
+	known := make(map[string]bool, len(seeds))
+	for _, sd := range seeds {
+		known[sd.Name()] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph seeds {\n")
+	b.WriteString("\tnode [shape=box, style=filled];\n")
+
+	for _, sd := range seeds {
+		color := "white"
+		if applied[sd.Name()] {
+			color = "palegreen"
+		}
+		fmt.Fprintf(&b, "\t%q [fillcolor=%s];\n", sd.Name(), color)
+	}
+
+	for _, sd := range seeds {
+		for _, dn := range sd.DependsOn() {
+			dep := dn
+			if !known[dep] {
+				dep = seedName(dn)
+			}
+			fmt.Fprintf(&b, "\t%q -> %q;\n", dep, sd.Name())
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// VerifySeeds runs the verification of every
+// registered seed implementing SeedVerifier,
+// each one in its own read-only transaction.
+// All failures are returned combined.
+func (s *Seeder) VerifySeeds() error {
+	ctx := context.Background()
+
+	err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	seeds, err := s.sortSeeds()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sd := range seeds {
+		v, ok := sd.Executor.(SeedVerifier)
+		if !ok {
+			continue
+		}
+
+		name := sd.Name()
+
+		tx, err := s.DB.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return fmt.Errorf("cannot start seed '%s' verification: %w", name, err)
+		}
+
+		err = safeCall(name, func() error {
+			return v.Verify(tx)
+		})
+		tx.Rollback()
+
+		if err != nil {
+			s.Log.Error(err, "Seed verification failed", "name", name)
+			errs = append(errs, fmt.Errorf("seed '%s' verification failed: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// MarkUnapplied removes the record of an applied seed
+// so that it is run again by next seeding.
+// Seed data is not modified.
+// Name can be either the seed function name or its snake case form.
+// As seeding, it is not allowed in production environment
+// unless 'seed.allowProduction' config value is true.
+func (s *Seeder) MarkUnapplied(name string) error {
+	err := s.checkEnv()
+	if err != nil {
+		return err
+	}
+
+	err = s.connect(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if sd, ok := s.findSeed(name); ok {
+		name = sd.Name()
+	}
+
+	st := s.tableSt(s.dialect.DelSeederSt())
+
+	r, err := s.DB.Exec(s.DB.Rebind(st), name)
+	if err != nil {
+		return fmt.Errorf("cannot update seeder table: %w", err)
+	}
+
+	n, err := r.RowsAffected()
+	if err == nil && n == 0 {
+		return fmt.Errorf("seed '%s' is not applied", name)
+	}
+
+	return nil
+}
+
+// AppliedSeeds returns the seeds recorded as applied
+// ordered by application time.
+// It returns an empty list if seeder table
+// has not been created yet.
+func (s *Seeder) AppliedSeeds() ([]AppliedSeed, error) {
+	applied := []AppliedSeed{}
+
+	exists, err := s.seedTableReady(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return applied, nil
+	}
+
+	st := s.tableSt(s.dialect.SelAppliedSt())
+
+	err = s.DB.Select(&applied, st)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read applied seeds: %w", err)
+	}
+
+	return applied, nil
+}
+
+// TotalSeeds returns the number of registered seeds.
+func (s *Seeder) TotalSeeds() int {
+	return len(s.seeds)
+}
+
+// PendingCount returns the number of registered seeds
+// not yet recorded as applied in seeder table.
+// As in DryRun, seeds not allowed in current environment
+// are not counted.
+// It fails with ErrSeedTableMissing if seeder table
+// has not been created yet.
+func (s *Seeder) PendingCount() (int, error) {
+	exists, err := s.seedTableReady(context.Background())
+	if err != nil {
+		return 0, err
+	}
+
+	if !exists {
+		return 0, ErrSeedTableMissing
+	}
+
+	seeds, err := s.envSeeds(s.seeds)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, sd := range seeds {
+		need, err := s.needsApply(sd)
+		if err != nil {
+			return 0, err
+		}
+
+		if need {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// AppliedSeedsJSON writes to w the seeds recorded as applied,
+// ordered by application time, as a JSON array
+// of objects with their name, fx and appliedAt time.
+func (s *Seeder) AppliedSeedsJSON(w io.Writer) error {
+	applied, err := s.AppliedSeeds()
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(applied)
+}
+
+// applySeed runs a single seed in its own transaction
+// and records it as applied.
+func (s *Seeder) applySeed(ctx context.Context, sd *Seed) (res SeedResult) {
+	res.Name = sd.Name()
+
+	pending, err := s.isPending(sd)
+	if err != nil || !pending {
+		res.Err = err
+		return res
+	}
+
+	// Seed transaction is bound to seed timeout
+	// so that it is reverted once exceeded.
+	sctx, cancel := s.seedContext(ctx, sd)
+	defer cancel()
+
+	if nonTransactional(sd.Executor) {
+		res.Duration, res.Err = s.runSeed(ctx, sctx, nil, sd, true)
+		res.RowsAffected = rowsAffected(sd.Executor)
+		res.Applied = res.Err == nil
+		return res
+	}
+
+	// Get a new Tx from seeder
+	tx, err := s.DB.BeginTxx(sctx, txOptions(sd.Executor))
+	if err != nil {
+		res.Err = fmt.Errorf("cannot start seed '%s' transaction: %w", res.Name, err)
+		return res
+	}
+
+	res.Duration, err = s.runSeed(ctx, sctx, tx, sd, true)
+	res.RowsAffected = rowsAffected(sd.Executor)
+	if err != nil {
+		s.rollback(tx)
+		res.Err = err
+		return res
+	}
+
+	err = s.commit(tx)
+	if err != nil {
+		s.Log.Error(err, "Commit error", "name", res.Name)
+		s.rollback(tx)
+		res.Err = SeedError{Name: res.Name, Op: "commit", Err: err}
+		return res
+	}
+
+	res.Applied = true
+	return res
+}
+
+// seedAll runs all seeds in a single transaction
+// committed only if every one of them succeeds.
+func (s *Seeder) seedAll(ctx context.Context, seeds []*Seed) ([]SeedResult, error) {
+	err := requireTx(seeds)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start seeding transaction: %w", err)
+	}
+
+	err = s.setSeederSchema(ctx, tx)
+	if err != nil {
+		s.rollback(tx)
+		return nil, fmt.Errorf("cannot set seeding schema: %w", err)
+	}
+
+	if s.BeforeAll != nil {
+		err = s.BeforeAll(tx)
+		if err != nil {
+			s.rollback(tx)
+			return nil, fmt.Errorf("before all hook failed: %w", err)
+		}
+	}
+
+	results := make([]SeedResult, 0, len(seeds))
+
+	// fail reverts tx, seeds applied in it so far
+	// are reported as failed with the cause.
+	fail := func(err error) ([]SeedResult, error) {
+		s.rollback(tx)
+		for i := range results {
+			if results[i].Applied {
+				results[i].Applied = false
+				results[i].Err = fmt.Errorf("rolled back: %w", err)
+			}
+		}
+		return results, err
+	}
+
+	for i, sd := range seeds {
+		s.step(sd, i)
+		res := SeedResult{Name: sd.Name()}
+
+		pending, err := s.isPending(sd)
+		if err != nil {
+			res.Err = err
+			results = append(results, res)
+			return fail(err)
+		}
+
+		if pending {
+			// Shared transaction is not bound to seed timeout,
+			// only seed function context is.
+			sctx, cancel := s.seedContext(ctx, sd)
+			res.Duration, err = s.runSeed(ctx, sctx, tx, sd, true)
+			cancel()
+			res.RowsAffected = rowsAffected(sd.Executor)
+			if err != nil {
+				res.Err = err
+				results = append(results, res)
+				return fail(err)
+			}
+		}
+
+		res.Applied = pending
+		results = append(results, res)
+	}
+
+	if s.AfterAll != nil {
+		err = s.AfterAll(tx)
+		if err != nil {
+			return fail(fmt.Errorf("after all hook failed: %w", err))
+		}
+	}
+
+	err = s.commit(tx)
+	if err != nil {
+		s.Log.Error(err, "Commit error")
+		return fail(fmt.Errorf("commit error: %w", err))
+	}
+
+	return results, nil
+}
+
+// LastStats returns the counters of last seeding run
+// started by Seed, SeedReport, SeedTagged or SeedOne
+// and their context variants.
+func (s *Seeder) LastStats() Stats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	return s.stats
+}
+
+func (s *Seeder) resetStats() {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	s.stats = Stats{}
+}
+
+// countResults adds the outcome of results to stats.
+func (s *Seeder) countResults(results []SeedResult) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			s.stats.Failed++
+		case r.Applied:
+			s.stats.Applied++
+		default:
+			s.stats.Skipped++
+		}
+	}
+}
+
+// commit commits tx counting it in stats.
+func (s *Seeder) commit(tx *sqlx.Tx) error {
+	err := tx.Commit()
+	if err == nil {
+		s.statsMu.Lock()
+		s.stats.Committed++
+		s.statsMu.Unlock()
+	}
+	return err
+}
+
+// rollback reverts tx counting it in stats.
+func (s *Seeder) rollback(tx *sqlx.Tx) error {
+	err := tx.Rollback()
+	if err == nil {
+		s.statsMu.Lock()
+		s.stats.RolledBack++
+		s.statsMu.Unlock()
+	}
+	return err
+}
+
+// isPending returns true if seed needs to be applied
+// or seeder is configured to force its execution.
+func (s *Seeder) isPending(sd *Seed) (bool, error) {
+	if s.Force {
+		return true, nil
+	}
+
+	need, err := s.needsApply(sd)
+	if err != nil || need {
+		return need, err
+	}
+
+	s.Log.Info("Seed already applied", "name", sd.Name())
+	return false, s.checkChecksum(sd)
+}
+
+// needsApply returns true if seed was not applied yet or,
+// if it implements SeedReapplier, it changed since it was.
+func (s *Seeder) needsApply(sd *Seed) (bool, error) {
+	name := sd.Name()
+
+	can, err := s.canApplySeed(name)
+	if err != nil {
+		return false, fmt.Errorf("cannot determine seed '%s' status: %w", name, err)
+	}
+
+	if can || !reapplyOnChange(sd.Executor) {
+		return can, nil
+	}
+
+	return s.seedChanged(sd)
+}
+
+// reapplyOnChange tells if the executor must be
+// reapplied when its checksum changes.
+func reapplyOnChange(e SeedExec) bool {
+	r, ok := e.(SeedReapplier)
+	return ok && r.ReapplyOnChange()
+}
+
+// checkChecksum compares stored checksum of an applied seed
+// with the current one, a mismatch is logged as a warning
+// or returned as an error in strict mode.
+func (s *Seeder) checkChecksum(sd *Seed) error {
+	changed, err := s.seedChanged(sd)
+	if err != nil || !changed {
+		return err
+	}
+
+	name := sd.Name()
+
+	if s.StrictChecksum {
+		return fmt.Errorf("seed '%s' changed since it was applied", name)
+	}
+
+	s.Log.Warn("Seed changed since it was applied", "name", name)
+	return nil
+}
+
+// seedChanged returns true if stored checksum of an applied seed
+// differs from the current one.
+// Seeds without a stored or current checksum are not checked.
+func (s *Seeder) seedChanged(sd *Seed) (bool, error) {
+	name := sd.Name()
+
+	sum := checksum(sd.Executor)
+	if sum == "" {
+		return false, nil
+	}
+
+	st := s.tableSt(s.dialect.SelChecksumSt())
+
+	var stored sql.NullString
+	err := s.DB.QueryRow(s.DB.Rebind(st), name).Scan(&stored)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("cannot read seed '%s' checksum: %w", name, err)
+	}
+
+	if !stored.Valid || stored.String == "" {
+		return false, nil
+	}
+
+	return stored.String != sum, nil
+}
+
+// seedContext returns a context derived from ctx
+// bound to seed timeout, if any.
+func (s *Seeder) seedContext(ctx context.Context, sd *Seed) (context.Context, context.CancelFunc) {
+	if to := s.seedTimeout(sd); to > 0 {
+		return context.WithTimeout(ctx, to)
+	}
+	return context.WithCancel(ctx)
+}
+
+// runSeed executes a seed using tx and records it as applied
+// if own is true. Otherwise tx belongs to the caller,
+// its search path and statement timeout are left as they are
+// and the seed is not recorded.
+// Seed function receives sctx, bound to seed timeout,
+// parent ctx is still used to tell a timeout from an aborted seeding.
+// Transaction is neither committed nor reverted.
+// It returns the time spent executing the seed function.
+func (s *Seeder) runSeed(ctx, sctx context.Context, tx *sqlx.Tx, sd *Seed, own bool) (time.Duration, error) {
+	exec := sd.Executor
+	fn := getFxName(exec.GetSeed())
+	name := sd.Name()
+
+	fx, err := seedFx(exec, fn)
+	if err != nil {
+		return 0, err
+	}
+
+	// Non transactional seeds have no tx to set up.
+	if tx != nil && own {
+		err = s.setupSeedTx(sctx, tx, sd)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	s.setupExec(sctx, tx, exec)
+
+	if s.BeforeEach != nil {
+		err = s.BeforeEach(name, tx)
+		if err != nil {
+			return 0, fmt.Errorf("before seed '%s' hook failed: %w", name, err)
+		}
+	}
+
+	// Execute seed
+	start := time.Now()
+	err = safeCall(name, fx)
+	d := time.Since(start)
+
+	if errors.Is(err, ErrSeedSkipped) {
+		s.Log.Info("Seed skipped, nothing to do", "name", name)
+		err = nil
+	}
+
+	if ctx.Err() == nil && errors.Is(sctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("seed '%s' timed out after %s: %w", name, d, sctx.Err())
+	}
+
+	if s.AfterEach != nil {
+		s.AfterEach(name, tx, err)
+	}
+
+	if err != nil {
+		s.Log.Error(err, "Seed step not executed", "name", fn, "type", fmt.Sprintf("%T", err))
+		return d, SeedError{Name: name, Op: "run", Err: err}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return d, fmt.Errorf("seeding aborted at '%s': %w", fn, err)
+	}
+
+	// Register seed
+	if own && tx == nil {
+		err = s.recSeedInTx(ctx, exec)
+		if err != nil {
+			return d, err
+		}
+	} else if own {
+		err = s.recSeed(exec)
+		if err != nil {
+			return d, err
+		}
+	}
+
+	s.Log.Debug("Seed step executed", "name", fn, "duration", d.String())
+	return d, nil
+}
+
+// recSeedInTx records a non transactional seed
+// as applied in its own transaction.
+func (s *Seeder) recSeedInTx(ctx context.Context, exec SeedExec) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return SeedError{Name: execName(exec), Op: "record", Err: err}
+	}
+
+	exec.SetTx(tx)
+	defer exec.SetTx(nil)
+
+	err = s.recSeed(exec)
+	if err != nil {
+		s.rollback(tx)
+		return err
+	}
+
+	err = s.commit(tx)
+	if err != nil {
+		return SeedError{Name: execName(exec), Op: "record", Err: err}
+	}
+
+	return nil
+}
+
+// requireTx returns an error if any of the seeds
+// must run outside a transaction.
+func requireTx(seeds []*Seed) error {
+	for _, sd := range seeds {
+		if nonTransactional(sd.Executor) {
+			return fmt.Errorf("seed '%s' is non transactional, it cannot run in a shared transaction", sd.Name())
+		}
+	}
+	return nil
+}
+
+// nonTransactional tells if the executor must run outside a transaction.
+func nonTransactional(e SeedExec) bool {
+	nt, ok := e.(SeedNonTransactional)
+	return ok && nt.NonTransactional()
+}
+
+// execSt runs st on tx reporting it to hook if not nil.
+func execSt(ctx context.Context, tx *sqlx.Tx, hook StatementHook, st string, args ...interface{}) (sql.Result, error) {
+	if hook == nil {
+		return tx.ExecContext(ctx, st, args...)
+	}
+
+	start := time.Now()
+	res, err := tx.ExecContext(ctx, st, args...)
+	hook(st, args, time.Since(start), err)
+	return res, err
+}
+
+// setupSeedTx sets the schema and statement timeout
+// of seed transaction tx.
+func (s *Seeder) setupSeedTx(ctx context.Context, tx *sqlx.Tx, sd *Seed) error {
+	var err error
+	if schema := seedSchema(sd.Executor); schema != "" {
+		err = s.setSchema(ctx, tx, schema)
+	} else {
+		err = s.setSeederSchema(ctx, tx)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot set seed '%s' schema: %w", sd.Name(), err)
+	}
+
+	err = s.setStatementTimeout(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("cannot set seed '%s' statement timeout: %w", sd.Name(), err)
+	}
+
+	return nil
+}
+
+// setupExec passes tx, ctx and seeder resources
+// to the executor before running its functions.
+func (s *Seeder) setupExec(ctx context.Context, tx *sqlx.Tx, exec SeedExec) {
+	exec.SetTx(tx)
+	exec.SetCtx(ctx)
+
+	if db, ok := exec.(SeedDBSetter); ok {
+		db.SetDB(s.DB)
+	}
+
+	if c, ok := exec.(SeedConfigurer); ok {
+		c.SetCfg(s.Cfg)
+	}
+
+	if l, ok := exec.(SeedLogger); ok {
+		l.SetLog(s.Log)
+	}
+
+	if d, ok := exec.(dialectUser); ok {
+		d.setDialect(s.dialect)
+	}
+
+	if h, ok := exec.(statementHooker); ok {
+		h.setStatementHook(s.StatementHook)
+	}
+}
+
+// safeCall calls fx converting a panic
+// into an error naming the seed.
+func safeCall(name string, fx SeedFx) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("seed '%s' panicked: %v", name, r)
+		}
+	}()
+
+	return fx()
+}
+
+// setSchema sets the search path of tx to schema.
+func (s *Seeder) setSchema(ctx context.Context, tx *sqlx.Tx, schema string) error {
+	err := validIdent(schema)
+	if err != nil {
+		return err
+	}
+
+	st := s.dialect.SetSchemaSt(schema)
+	if st == "" {
+		return fmt.Errorf("seed schema not supported by %s", s.dialect.DriverName())
+	}
+
+	_, err = tx.ExecContext(ctx, st)
+	return err
+}
+
+// setSeederSchema sets the search path of tx to seeder schema
+// so that it doesn't depend on connection settings.
+// It is a no-op for engines that don't support it.
+func (s *Seeder) setSeederSchema(ctx context.Context, tx *sqlx.Tx) error {
+	st := s.dialect.SetSchemaSt(s.schema)
+	if st == "" {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, st)
+	return err
+}
+
+// setStatementTimeout limits the time each statement of tx
+// is allowed to run to 'seed.statementTimeout' config value,
+// a duration or a number of milliseconds, zero disables it.
+// It is a no-op for engines that don't support it.
+func (s *Seeder) setStatementTimeout(ctx context.Context, tx *sqlx.Tx) error {
+	to := s.statementTimeout()
+	if to <= 0 {
+		return nil
+	}
+
+	st := s.dialect.StatementTimeoutSt(to)
+	if st == "" {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, st)
+	return err
+}
+
+// statementTimeout returns 'seed.statementTimeout' config value.
+func (s *Seeder) statementTimeout() time.Duration {
+	val := s.Cfg.ValOrDef("seed.statementTimeout", "0")
+
+	ms, err := strconv.ParseInt(val, 10, 64)
+	if err == nil {
+		return time.Duration(ms) * time.Millisecond
+	}
+
+	to, err := time.ParseDuration(val)
+	if err != nil {
+		s.Log.Error(err, "Invalid statement timeout")
+		return 0
+	}
+
+	return to
+}
+
+// seedTimeout returns the time a seed function is allowed to run.
+// It is read from 'seed.timeout' config value unless
+// the executor implements SeedTimeouter.
+func (s *Seeder) seedTimeout(sd *Seed) time.Duration {
+	if t, ok := sd.Executor.(SeedTimeouter); ok {
+		return t.Timeout()
+	}
+
+	to, err := time.ParseDuration(s.Cfg.ValOrDef("seed.timeout", "30s"))
+	if err != nil {
+		s.Log.Error(err, "Invalid seed timeout")
+		return 30 * time.Second
+	}
+
+	return to
+}
+
+// findSeed returns the registered seed
+// whose function name or seed name matches name.
+func (s *Seeder) findSeed(name string) (*Seed, bool) {
+	for _, sd := range s.seeds {
+		if sd.Name() == name {
+			return sd, true
+		}
+	}
+
+	for _, sd := range s.seeds {
+		if getFxName(sd.Executor.GetSeed()) == name {
+			return sd, true
+		}
+	}
+
+	return nil, false
+}
+
+// Unseed reverts applied seeds in reverse order.
+func (s *Seeder) Unseed() error {
+	return s.UnseedContext(context.Background())
+}
+
+// UnseedContext reverts applied seeds in reverse order.
+// Each seed is reverted in its own transaction, set up as
+// when seeding, seeds without unseed function are kept applied.
+// As seeding, it is not allowed in production environment
+// unless 'seed.allowProduction' config value is true.
+// Unseeding is aborted and in-flight transaction
+// reverted when context is done.
+func (s *Seeder) UnseedContext(ctx context.Context) error {
+	err := s.checkEnv()
+	if err != nil {
+		return err
+	}
+
+	err = s.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	seeds, err := s.sortSeeds()
+	if err != nil {
+		return err
+	}
+
+	for i := len(seeds) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("unseeding aborted: %w", err)
+		}
+
+		sd := seeds[i]
+		exec := sd.Executor
+		fn := getFxName(exec.GetUnseed())
+		// Seed name is associated to seed function
+		name := sd.Name()
+
+		// Continue if not applied
+		can, err := s.canApplySeed(name)
+		if err != nil {
+			return fmt.Errorf("cannot determine seed '%s' status: %w", name, err)
+		}
+
+		if can {
+			s.Log.Info("Seed not applied", "name", name)
+			continue
+		}
+
+		if exec.GetUnseed() == nil {
+			s.Log.Info("Seed has no unseed function, kept applied", "name", name)
+			continue
+		}
+
+		fx, err := seedFx(exec, fn)
+		if err != nil {
+			return err
+		}
+
+		// Get a new Tx from seeder
+		tx, err := s.DB.BeginTxx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("cannot start unseed '%s' transaction: %w", fn, err)
+		}
+
+		err = s.setupSeedTx(ctx, tx, sd)
+		if err != nil {
+			s.rollback(tx)
+			return err
+		}
+
+		s.setupExec(ctx, tx, exec)
+
+		// Execute unseed
+		err = safeCall(name, fx)
+		if err != nil {
+			s.rollback(tx)
+			s.Log.Error(err, "Unseed step not executed", "name", fn)
+			return SeedError{Name: name, Op: "revert", Err: err}
+		}
+
+		// Remove seed record
+		err = s.delSeed(exec)
+		if err != nil {
+			s.rollback(tx)
+			return err
+		}
+
+		err = s.commit(tx)
+		if err != nil {
+			s.rollback(tx)
+			return SeedError{Name: name, Op: "commit revert of", Err: err}
+		}
+
+		s.Log.Debug("Unseed step executed", "name", fn)
+	}
+
+	return nil
+}
+
+// canApplySeed returns true if there is
+// no applied record for the seed in seeder table.
+func (s *Seeder) canApplySeed(name string) (bool, error) {
+	st := s.tableSt(s.dialect.SelSeederSt())
+	r, err := s.DB.Query(s.DB.Rebind(st), name)
+
+	if err != nil {
+		s.Log.Error(err, "Cannot determine seeder status")
+		return false, err
+	}
+	defer r.Close()
+
 	for r.Next() {
 		var applied sql.NullBool
 		err = r.Scan(&applied)
 		if err != nil {
 			s.Log.Error(err, "Cannot determine seeder status")
-			return false
+			return false, err
 		}
 
-		return !applied.Bool
+		return !applied.Bool, nil
+	}
+
+	return true, r.Err()
+}
+
+// now returns current time using seeder clock.
+func (s *Seeder) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+// genID returns a new seed record ID using seeder generator.
+func (s *Seeder) genID() (string, error) {
+	if s.IDGen == nil {
+		return genUUID(), nil
 	}
 
-	return true
+	id := s.IDGen()
+	_, err := uuid.FromString(id)
+	if err != nil {
+		return "", fmt.Errorf("invalid seed record ID '%s': %w", id, err)
+	}
+
+	return id, nil
+}
+
+func genUUID() string {
+	return uuid.NewV4().String()
 }
 
+// recSeed records the executor as applied in its transaction.
 func (s *Seeder) recSeed(e SeedExec) error {
-	st := fmt.Sprintf(pgRecSeederSt, s.schema, pgSeederTable)
-	fx := getFxName(e.GetSeed())
-	name := seedName(fx)
-
-	_, err := e.GetTx().NamedExec(st, seedRecord{
-		ID:        uuid.NewV4(),
-		Name:      name,
-		Fx:        fx,
-		IsApplied: true,
-		CreatedAt: time.Now(),
+	sum := checksum(e)
+	schema := seedSchema(e)
+	tags := strings.Join(seedTags(e), ",")
+
+	return s.recordApplied(e.GetTx(), seedRecord{
+		Name:     execName(e),
+		Fx:       execFx(e),
+		Checksum: sql.NullString{String: sum, Valid: sum != ""},
+		Schema:   sql.NullString{String: schema, Valid: schema != ""},
+		Tags:     sql.NullString{String: tags, Valid: tags != ""},
 	})
+}
+
+// recordApplied inserts rec in seeder table using tx
+// with a new ID, marked as applied at current seeder time.
+// A previous record of the seed, i.e.: if forced, is replaced.
+func (s *Seeder) recordApplied(tx *sqlx.Tx, rec seedRecord) error {
+	id, err := s.genID()
+	if err != nil {
+		return fmt.Errorf("cannot record seed '%s': %w", rec.Name, err)
+	}
+
+	rec.ID = id
+	rec.IsApplied = true
+	rec.CreatedAt = s.now()
+
+	st := s.tableSt(s.dialect.DelSeederSt())
+
+	_, err = tx.Exec(tx.Rebind(st), rec.Name)
+	if err != nil {
+		s.Log.Error(err, "Cannot replace seed record", "name", rec.Name)
+		return SeedError{Name: rec.Name, Op: "record", Err: err}
+	}
+
+	st = s.tableSt(s.dialect.RecSeederSt())
 
+	_, err = tx.NamedExec(st, rec)
 	if err != nil {
-		fmt.Println(err)
-		msg := fmt.Sprintf("Cannot update seeder table: %s\n", err.Error())
-		return errors.New(msg)
+		s.Log.Error(err, "Cannot record seed", "name", rec.Name)
+		return SeedError{Name: rec.Name, Op: "record", Err: err}
+	}
+
+	return nil
+}
+
+func (s *Seeder) delSeed(e SeedExec) error {
+	st := s.tableSt(s.dialect.DelSeederSt())
+	name := execName(e)
+
+	_, err := e.GetTx().Exec(s.DB.Rebind(st), name)
+	if err != nil {
+		return SeedError{Name: name, Op: "delete record of", Err: err}
+	}
+
+	return nil
+}
+
+// sortSeeds returns registered seeds ordered
+// so that each one comes after its dependencies.
+// Among seeds whose dependencies are satisfied structure
+// ones come first, then the one with the lowest priority,
+// insertion order is preserved between the rest.
+func (s *Seeder) sortSeeds() ([]*Seed, error) {
+	idx := make(map[string]int, len(s.seeds))
+	for i, sd := range s.seeds {
+		if j, ok := idx[sd.Name()]; ok {
+			return nil, fmt.Errorf("duplicate seed name '%s': registered at positions %d and %d", sd.Name(), j, i)
+		}
+		idx[sd.Name()] = i
+	}
+
+	deps := make([][]int, len(s.seeds))
+	for i, sd := range s.seeds {
+		for _, dn := range sd.DependsOn() {
+			j, ok := idx[dn]
+			if !ok {
+				j, ok = idx[seedName(dn)]
+			}
+
+			if !ok {
+				return nil, fmt.Errorf("seed '%s' depends on unknown seed '%s'", sd.Name(), dn)
+			}
+			deps[i] = append(deps[i], j)
+		}
+	}
+
+	sorted := make([]*Seed, 0, len(s.seeds))
+	done := make([]bool, len(s.seeds))
+
+	for len(sorted) < len(s.seeds) {
+		next := -1
+
+		for i := range s.seeds {
+			if done[i] {
+				continue
+			}
+
+			ready := true
+			for _, j := range deps[i] {
+				if !done[j] {
+					ready = false
+					break
+				}
+			}
+
+			if ready && (next < 0 || runsBefore(s.seeds[i].Executor, s.seeds[next].Executor)) {
+				next = i
+			}
+		}
+
+		if next < 0 {
+			var pending []string
+			for i, sd := range s.seeds {
+				if !done[i] {
+					pending = append(pending, sd.Name())
+				}
+			}
+			return nil, fmt.Errorf("seed dependency cycle among: %s", strings.Join(pending, ", "))
+		}
+
+		done[next] = true
+		sorted = append(sorted, s.seeds[next])
+	}
+
+	return sorted, nil
+}
+
+// duplicateSeeds returns the names shared
+// by more than one registered seed.
+func (s *Seeder) duplicateSeeds() []string {
+	var dups []string
+	count := make(map[string]int, len(s.seeds))
+	for _, sd := range s.seeds {
+		name := sd.Name()
+		count[name]++
+		if count[name] == 2 {
+			dups = append(dups, name)
+		}
+	}
+	return dups
+}
+
+// Name returns the seed name.
+func (sd *Seed) Name() string {
+	return execName(sd.Executor)
+}
+
+// DependsOn returns the name of the seeds
+// this one depends on, if any.
+func (sd *Seed) DependsOn() []string {
+	d, ok := sd.Executor.(SeedDependent)
+	if !ok {
+		return nil
+	}
+
+	return d.DependsOn()
+}
+
+// HasTag returns true if seed is tagged
+// with any of tags.
+func (sd *Seed) HasTag(tags ...string) bool {
+	for _, t := range seedTags(sd.Executor) {
+		for _, tag := range tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// seedFx returns the executor method named fn.
+// It fails if fn is not an exported method
+// of the executor with SeedFx signature,
+// i.e.: a closure or an unexported method.
+func seedFx(exec SeedExec, fn string) (SeedFx, error) {
+	if fn == "" {
+		return nil, errors.New("seed fx not set")
+	}
+
+	m := reflect.ValueOf(exec).MethodByName(fn)
+	if !m.IsValid() {
+		return nil, fmt.Errorf("seed fx %q is not an exported method", fn)
+	}
+
+	fx, ok := m.Interface().(SeedFx)
+	if !ok {
+		return nil, fmt.Errorf("seed fx %q is not a func() error method", fn)
+	}
+
+	return fx, nil
+}
+
+// execName returns the name used to track
+// the executor in seeder table.
+func execName(e SeedExec) string {
+	if n, ok := e.(SeedRecordNamer); ok && n.RecordName() != "" {
+		return n.RecordName()
 	}
 
+	return seedName(getFxName(e.GetSeed()))
+}
+
+// execFx returns the fx stored in seeder table for the executor,
+// its record name if it implements SeedRecordNamer
+// or its seed function name otherwise.
+func execFx(e SeedExec) string {
+	if n, ok := e.(SeedRecordNamer); ok && n.RecordName() != "" {
+		return n.RecordName()
+	}
+
+	return getFxName(e.GetSeed())
+}
+
+// txOptions returns the options used to start
+// the executor transaction, nil if it doesn't
+// implement SeedIsolator.
+func txOptions(e SeedExec) *sql.TxOptions {
+	if i, ok := e.(SeedIsolator); ok {
+		return &sql.TxOptions{Isolation: i.IsolationLevel()}
+	}
+	return nil
+}
+
+// rowsAffected returns the rows affected by the executor,
+// zero if it doesn't implement SeedRowCounter.
+func rowsAffected(e SeedExec) int64 {
+	if c, ok := e.(SeedRowCounter); ok {
+		return c.RowsAffected()
+	}
+	return 0
+}
+
+// seedTags returns the tags declared by the executor,
+// none if it doesn't implement SeedTagger.
+func seedTags(e SeedExec) []string {
+	if t, ok := e.(SeedTagger); ok {
+		return t.Tags()
+	}
 	return nil
 }
 
+// runsBefore tells if a should run before b when both are ready,
+// by phase first and priority then.
+func runsBefore(a, b SeedExec) bool {
+	pa, pb := phaseRank(a), phaseRank(b)
+	if pa != pb {
+		return pa < pb
+	}
+	return seedPriority(a) < seedPriority(b)
+}
+
+// phaseRank returns the position of the executor phase,
+// structure seeds run before data ones.
+func phaseRank(e SeedExec) int {
+	if p, ok := e.(SeedPhaser); ok && p.Phase() == PhaseStructure {
+		return 0
+	}
+	return 1
+}
+
+// seedPriority returns the priority declared by the executor,
+// zero if it doesn't implement SeedPrioritizer.
+func seedPriority(e SeedExec) int {
+	if p, ok := e.(SeedPrioritizer); ok {
+		return p.Priority()
+	}
+	return 0
+}
+
+// seedSchema returns the schema declared by the executor,
+// empty if it doesn't implement SeedSchemer.
+func seedSchema(e SeedExec) string {
+	if sc, ok := e.(SeedSchemer); ok {
+		return sc.Schema()
+	}
+	return ""
+}
+
+// checksum returns a hash of the executor version,
+// empty if it doesn't implement SeedVersioner.
+func checksum(e SeedExec) string {
+	v, ok := e.(SeedVersioner)
+	if !ok {
+		return ""
+	}
+
+	ver := v.Version()
+	if ver == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(ver))
+	return hex.EncodeToString(sum[:])
+}
+
 func seedName(fxName string) string {
 	return toSnakeCase(fxName)
 }
 
+// dbURL returns seeder database DSN.
+// Schema and database name are set once by NewSeeder.
 func (m *Seeder) dbURL() string {
-	host := m.Cfg.ValOrDef("pg.host", "localhost")
-	port := m.Cfg.ValOrDef("pg.port", "5432")
-	m.schema = m.Cfg.ValOrDef("pg.schema", "public")
-	m.dbName = m.Cfg.ValOrDef("pg.database", "kabestan_test_d1x89s0l")
-	user := m.Cfg.ValOrDef("pg.user", "kabestan")
-	pass := m.Cfg.ValOrDef("pg.password", "kabestan")
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbName=%s sslmode=disable search_path=%s", host, port, user, pass, m.dbName, m.schema)
+	return m.dialect.DSN(m.Cfg)
 }
 
+// pgDbURL returns admin database DSN.
 func (m *Seeder) pgDbURL() string {
-	host := m.Cfg.ValOrDef("pg.host", "localhost")
-	port := m.Cfg.ValOrDef("pg.port", "5432")
-	schema := "public"
-	db := "postgres"
-	user := m.Cfg.ValOrDef("pg.user", "kabestan")
-	pass := m.Cfg.ValOrDef("pg.password", "kabestan")
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbName=%s sslmode=disable search_path=%s", host, port, user, pass, db, schema)
+	return m.dialect.AdminDSN(m.Cfg)
 }
@@ -0,0 +1,823 @@
+package kabestan
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type testLogger struct{}
+
+func (l testLogger) Debug(meta ...interface{})            {}
+func (l testLogger) Info(meta ...interface{})             {}
+func (l testLogger) Warn(meta ...interface{})             {}
+func (l testLogger) Error(err error, meta ...interface{}) {}
+
+// testSeed runs fx, if set, counting its calls.
+type testSeed struct {
+	BaseSeed
+	name  string
+	deps  []string
+	fx    func() error
+	calls int
+}
+
+func newTestSeed(name string, fx func() error, deps ...string) *testSeed {
+	s := &testSeed{name: name, fx: fx, deps: deps}
+	s.Config(s.SeedData, s.UnseedData)
+	return s
+}
+
+func (s *testSeed) SeedData() error {
+	s.calls++
+	if s.fx != nil {
+		return s.fx()
+	}
+	return nil
+}
+
+func (s *testSeed) UnseedData() error {
+	return nil
+}
+
+func (s *testSeed) RecordName() string {
+	return s.name
+}
+
+func newTestSeeder(values map[string]string) *Seeder {
+	return NewSeeder(testConfig(values), testLogger{}, "test-seeder", nil)
+}
+
+func TestNewSeederDefaults(t *testing.T) {
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+
+	if s.schema != "public" {
+		t.Errorf("schema = %q, want %q", s.schema, "public")
+	}
+
+	if s.dbName != "kabestan" {
+		t.Errorf("dbName = %q, want %q", s.dbName, "kabestan")
+	}
+
+	for _, id := range []string{s.dbName, s.schema, s.tableName()} {
+		if err := validIdent(id); err != nil {
+			t.Errorf("default identifier %q is not valid: %v", id, err)
+		}
+	}
+}
+
+func TestRecordAppliedReplacesRecord(t *testing.T) {
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+
+	for i := 0; i < 2; i++ {
+		tx, err := s.BeginTx()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = s.recordApplied(tx, seedRecord{Name: "create_users", Fx: "CreateUsers"})
+		if err != nil {
+			t.Fatalf("record error: %v", err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dels := f.stmts("DELETE FROM")
+	ins := f.stmts("INSERT INTO")
+	if len(dels) != 2 || len(ins) != 2 {
+		t.Fatalf("got %d deletes and %d inserts, want 2 and 2", len(dels), len(ins))
+	}
+
+	for _, d := range dels {
+		if len(d.args) != 1 || d.args[0] != "create_users" {
+			t.Errorf("delete args = %v, want seed name", d.args)
+		}
+	}
+
+	// Each insert is preceded by the delete of previous record.
+	var order []string
+	for _, e := range f.stmts("") {
+		order = append(order, strings.Fields(e.st)[0])
+	}
+	if strings.Join(order, ",") != "DELETE,INSERT,DELETE,INSERT" {
+		t.Errorf("statements order = %v", order)
+	}
+}
+
+func TestSeedInTxKeepsCallerSession(t *testing.T) {
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test", "seed.statementTimeout": "2s"})
+	s.DB = db
+	sd := newTestSeed("users", nil)
+	s.AddSeed(sd)
+
+	tx, err := db.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	err = s.SeedInTx(tx)
+	if err != nil {
+		t.Fatalf("seed error: %v", err)
+	}
+
+	if sd.calls != 1 {
+		t.Errorf("seed called %d times, want 1", sd.calls)
+	}
+
+	if n := len(f.stmts("SET LOCAL")); n > 0 {
+		t.Errorf("%d session statements issued on caller transaction", n)
+	}
+
+	if n := len(f.stmts("INSERT INTO")); n > 0 {
+		t.Errorf("%d seed records inserted", n)
+	}
+}
+
+func TestUnseedNotAllowedInProduction(t *testing.T) {
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "production"})
+	s.DB = db
+	s.AddSeed(newTestSeed("users", nil))
+
+	if err := s.Unseed(); err == nil {
+		t.Error("Unseed: expected an error in production")
+	}
+
+	if err := s.MarkUnapplied("users"); err == nil {
+		t.Error("MarkUnapplied: expected an error in production")
+	}
+
+	if n := len(f.stmts("")); n > 0 {
+		t.Errorf("%d statements executed in production", n)
+	}
+}
+
+func TestSeedGraphColorsAppliedSeeds(t *testing.T) {
+	f, db := newFakeDB()
+	f.query = func(st string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		if strings.Contains(st, "SELECT is_applied") {
+			if args[0] == "users" {
+				return []string{"is_applied"}, [][]driver.Value{{true}}, nil
+			}
+			return []string{"is_applied"}, nil, nil
+		}
+		return []string{"exists"}, [][]driver.Value{{true}}, nil
+	}
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+	s.AddSeed(newTestSeed("users", nil))
+	s.AddSeed(&prodSeed{newTestSeed("accounts", nil)})
+
+	g, err := s.SeedGraph()
+	if err != nil {
+		t.Fatalf("graph error: %v", err)
+	}
+
+	for _, want := range []string{`"users" [fillcolor=palegreen]`, `"accounts" [fillcolor=white]`} {
+		if !strings.Contains(g, want) {
+			t.Errorf("graph does not contain %s:\n%s", want, g)
+		}
+	}
+}
+
+// prodSeed is a testSeed only allowed in production.
+type prodSeed struct {
+	*testSeed
+}
+
+func (s *prodSeed) Environments() []string {
+	return []string{"production"}
+}
+
+func TestCloneDoesNotShareExecutors(t *testing.T) {
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	orig := newTestSeed("users", nil)
+	s.AddSeed(orig)
+
+	exec := newTestSeed("users", nil)
+	c := s.Clone(exec)
+
+	if c.TotalSeeds() != 1 || c.seeds[0].Executor != exec {
+		t.Fatalf("clone seeds are not the given executors")
+	}
+
+	c.AddSeed(newTestSeed("accounts", nil))
+	if s.TotalSeeds() != 1 {
+		t.Errorf("seed added to clone added to original")
+	}
+
+	if s.seeds[0].Executor != orig {
+		t.Errorf("original executors changed")
+	}
+}
+
+// depSeed is a testSeed that declares its dependencies.
+type depSeed struct {
+	*testSeed
+}
+
+func (s *depSeed) DependsOn() []string {
+	return s.deps
+}
+
+func TestContinueOnErrorSkipsDependents(t *testing.T) {
+	_, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test", "seed.parallelism": "1"})
+	s.DB = db
+	s.ContinueOnError = true
+
+	failing := newTestSeed("accounts", func() error { return errors.New("boom") })
+	dependent := &depSeed{newTestSeed("profiles", nil, "accounts")}
+	other := newTestSeed("countries", nil)
+	s.RegisterSeeds(failing, dependent, other)
+
+	results, err := s.SeedReport()
+	if err == nil {
+		t.Fatal("expected the failure to be returned")
+	}
+
+	if dependent.calls != 0 {
+		t.Errorf("dependent of failed seed was run")
+	}
+
+	if other.calls != 1 {
+		t.Errorf("independent seed run %d times, want 1", other.calls)
+	}
+
+	byName := map[string]SeedResult{}
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+
+	if r := byName["profiles"]; !r.Skipped || r.Applied || r.Err != nil {
+		t.Errorf("dependent result = %+v, want skipped", r)
+	}
+
+	st := s.LastStats()
+	if st.Applied != 1 || st.Failed != 1 || st.Skipped != 1 {
+		t.Errorf("stats = %+v, want 1 applied, 1 failed and 1 skipped", st)
+	}
+}
+
+func TestTransactionalRollbackReportsFailures(t *testing.T) {
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+	s.Transactional = true
+	s.AfterAll = func(tx *sqlx.Tx) error {
+		return errors.New("boom")
+	}
+	s.RegisterSeeds(newTestSeed("accounts", nil), newTestSeed("countries", nil))
+
+	results, err := s.SeedReport()
+	if err == nil {
+		t.Fatal("expected after all hook error")
+	}
+
+	for _, r := range results {
+		if r.Applied || r.Err == nil {
+			t.Errorf("result %+v is not reported as failed", r)
+		}
+	}
+
+	st := s.LastStats()
+	if st.Failed != 2 || st.Skipped != 0 || st.Applied != 0 {
+		t.Errorf("stats = %+v, want 2 failed", st)
+	}
+
+	if n := f.open(); n != 0 {
+		t.Errorf("%d transactions left open", n)
+	}
+}
+
+// unexportedSeed uses an unexported method as seed function.
+type unexportedSeed struct {
+	*testSeed
+}
+
+func (s *unexportedSeed) seedData() error {
+	return nil
+}
+
+func TestSeedFxNotExportedMethod(t *testing.T) {
+	closure := newTestSeed("closure", nil)
+	closure.Config(func() error { return nil }, nil)
+
+	unexported := &unexportedSeed{newTestSeed("unexported", nil)}
+	unexported.Config(unexported.seedData, nil)
+
+	for _, e := range []SeedExec{closure, unexported} {
+		_, err := seedFx(e, getFxName(e.GetSeed()))
+		if err == nil || !strings.Contains(err.Error(), "not an exported method") {
+			t.Errorf("seed fx %q error = %v, want not an exported method", getFxName(e.GetSeed()), err)
+		}
+	}
+
+	_, err := seedFx(closure, "SeedData")
+	if err != nil {
+		t.Errorf("exported method error: %v", err)
+	}
+}
+
+func TestPanickingSeed(t *testing.T) {
+	err := safeCall("users", func() error { panic("boom") })
+	if err == nil || !strings.Contains(err.Error(), "'users'") {
+		t.Errorf("safe call error = %v, want it naming the seed", err)
+	}
+
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+	s.AddSeed(newTestSeed("users", func() error { panic("boom") }))
+
+	res := s.applySeed(context.Background(), s.seeds[0])
+	if res.Err == nil || res.Applied {
+		t.Errorf("result = %+v, want panic reported as error", res)
+	}
+
+	if f.rolledBack != 1 || f.open() != 0 {
+		t.Errorf("seed transaction not reverted: %d rollbacks, %d open", f.rolledBack, f.open())
+	}
+}
+
+func TestDuplicateSeedNames(t *testing.T) {
+	s := newTestSeeder(map[string]string{"app.env": "test", "db.url": "postgres://localhost/app"})
+	s.RegisterSeeds(newTestSeed("users", nil), newTestSeed("accounts", nil), newTestSeed("users", nil))
+
+	_, err := s.sortSeeds()
+	if err == nil || !strings.Contains(err.Error(), "'users'") {
+		t.Errorf("sort error = %v, want duplicate 'users'", err)
+	}
+
+	err = s.Validate()
+	if err == nil || !strings.Contains(err.Error(), "seed name 'users' is registered more than once") {
+		t.Errorf("validate error = %v, want duplicate 'users'", err)
+	}
+
+	if err != nil && strings.Contains(err.Error(), "'accounts'") {
+		t.Errorf("unique seed reported as duplicate: %v", err)
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	tests := []struct {
+		val  string
+		want time.Duration
+	}{
+		{"0", 0},
+		{"1500", 1500 * time.Millisecond},
+		{"2s", 2 * time.Second},
+		{"invalid", 0},
+	}
+
+	for _, tt := range tests {
+		s := newTestSeeder(map[string]string{"app.env": "test", "seed.statementTimeout": tt.val})
+		if got := s.statementTimeout(); got != tt.want {
+			t.Errorf("statementTimeout(%q) = %v, want %v", tt.val, got, tt.want)
+		}
+	}
+
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test", "seed.statementTimeout": "2s"})
+	s.DB = db
+	s.AddSeed(newTestSeed("users", nil))
+
+	res := s.applySeed(context.Background(), s.seeds[0])
+	if res.Err != nil {
+		t.Fatalf("seed error: %v", res.Err)
+	}
+
+	sts := f.stmts("SET LOCAL statement_timeout")
+	if len(sts) != 1 || !strings.Contains(sts[0].st, "2000") {
+		t.Errorf("statement timeout statements = %v, want one of 2000ms", sts)
+	}
+}
+
+func TestCreateSeederTableTwice(t *testing.T) {
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.createSeederTable(); err != nil {
+			t.Fatalf("create seeder table error on run %d: %v", i+1, err)
+		}
+	}
+
+	for _, e := range f.stmts("CREATE TABLE") {
+		if !strings.Contains(e.st, "IF NOT EXISTS") {
+			t.Errorf("create table statement not idempotent: %s", e.st)
+		}
+	}
+
+	if f.committed != 2 || f.open() != 0 {
+		t.Errorf("%d commits and %d open transactions, want 2 and 0", f.committed, f.open())
+	}
+}
+
+func TestRecordAppliedValues(t *testing.T) {
+	f, db := newFakeDB()
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	id := "c0a80121-7ac0-4e1c-8a2b-1f2a3b4c5d6e"
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+	s.Now = func() time.Time { return now }
+	s.IDGen = func() string { return id }
+
+	tx, err := s.BeginTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.recordApplied(tx, seedRecord{Name: "create_users", Fx: "CreateUsers"})
+	if err != nil {
+		t.Fatalf("record error: %v", err)
+	}
+	tx.Commit()
+
+	ins := f.stmts("INSERT INTO")
+	if len(ins) != 1 {
+		t.Fatalf("got %d inserts, want 1", len(ins))
+	}
+
+	// id, name, fx, checksum, schema_name, tags, is_applied, created_at
+	args := ins[0].args
+	if len(args) != 8 {
+		t.Fatalf("got %d insert args, want 8", len(args))
+	}
+
+	if args[0] != id || args[1] != "create_users" || args[2] != "CreateUsers" {
+		t.Errorf("record id, name and fx = %v, %v, %v", args[0], args[1], args[2])
+	}
+
+	if args[6] != true {
+		t.Errorf("is_applied = %v, want true", args[6])
+	}
+
+	if at, ok := args[7].(time.Time); !ok || !at.Equal(now) {
+		t.Errorf("created_at = %v, want %v", args[7], now)
+	}
+
+	s.IDGen = func() string { return "not-a-uuid" }
+	tx, _ = s.BeginTx()
+	defer tx.Rollback()
+
+	if err := s.recordApplied(tx, seedRecord{Name: "create_users"}); err == nil {
+		t.Error("expected an error for invalid record ID")
+	}
+}
+
+func TestFailingSeedRollsBack(t *testing.T) {
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+
+	boom := errors.New("boom")
+	s.AddSeed(newTestSeed("users", func() error { return boom }))
+
+	err := s.SeedContext(context.Background())
+	if !errors.Is(err, boom) {
+		t.Fatalf("seed error = %v, want seed function error", err)
+	}
+
+	if n := len(f.stmts("INSERT INTO")); n > 0 {
+		t.Errorf("failed seed recorded as applied")
+	}
+
+	if f.rolledBack == 0 || f.open() != 0 {
+		t.Errorf("seed transaction not reverted: %d rollbacks, %d open", f.rolledBack, f.open())
+	}
+}
+
+func TestSeederSearchPath(t *testing.T) {
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test", "pg.schema": "sales"})
+	s.DB = db
+	s.AddSeed(newTestSeed("users", nil))
+
+	if _, err := s.createSeederTable(); err != nil {
+		t.Fatalf("create seeder table error: %v", err)
+	}
+
+	if res := s.applySeed(context.Background(), s.seeds[0]); res.Err != nil {
+		t.Fatalf("seed error: %v", res.Err)
+	}
+
+	// One in seeder table creation and another one in seed transaction.
+	sts := f.stmts(`SET LOCAL search_path TO "sales"`)
+	if len(sts) != 2 {
+		t.Errorf("got %d search path statements, want 2", len(sts))
+	}
+}
+
+func TestCloseOwnedConnection(t *testing.T) {
+	_, owned := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.conn = owned
+	s.DB = owned
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	if err := owned.Ping(); err == nil {
+		t.Error("owned connection left open")
+	}
+
+	if s.DB != nil {
+		t.Error("closed connection still in use")
+	}
+
+	_, injected := newFakeDB()
+
+	s = newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = injected
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	if err := injected.Ping(); err != nil {
+		t.Errorf("injected connection closed: %v", err)
+	}
+}
+
+func TestCreateSeederTableUpgradesColumns(t *testing.T) {
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+
+	if _, err := s.createSeederTable(); err != nil {
+		t.Fatalf("create seeder table error: %v", err)
+	}
+
+	alters := f.stmts("ADD COLUMN IF NOT EXISTS")
+	if len(alters) != 3 {
+		t.Fatalf("got %d column upgrades, want 3", len(alters))
+	}
+
+	for i, col := range []string{"checksum", "schema_name", "tags"} {
+		if !strings.Contains(alters[i].st, `ALTER TABLE "public"."seeds"`) || !strings.Contains(alters[i].st, col) {
+			t.Errorf("upgrade statement %d = %s, want column %s added to seeder table", i, alters[i].st, col)
+		}
+	}
+}
+
+func TestAdminStatementsUseAdminConnection(t *testing.T) {
+	app, appDB := newFakeDB()
+	admin, adminDB := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = appDB
+	s.admin = adminDB
+
+	if _, err := s.DropDb(); err != nil {
+		t.Fatalf("drop error: %v", err)
+	}
+
+	if _, err := s.CreateDb(); err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+
+	if err := s.CloseAppConns(); err != nil {
+		t.Fatalf("close app connections error: %v", err)
+	}
+
+	if n := len(app.stmts("")); n > 0 {
+		t.Errorf("%d admin statements run on injected database: %v", n, app.stmts(""))
+	}
+
+	for _, sub := range []string{"DROP DATABASE", "CREATE DATABASE", "pg_terminate_backend"} {
+		if len(admin.stmts(sub)) == 0 {
+			t.Errorf("%s not run on admin database", sub)
+		}
+	}
+
+	if s.DB != appDB {
+		t.Error("injected database replaced")
+	}
+}
+
+// panickingUnseed is a testSeed whose unseed function panics.
+type panickingUnseed struct {
+	*testSeed
+}
+
+func (s *panickingUnseed) UnseedData() error {
+	var m map[string]int
+	m["boom"]++
+	return nil
+}
+
+func TestPanickingUnseed(t *testing.T) {
+	f, db := newFakeDB()
+	f.query = func(st string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		return []string{"is_applied"}, [][]driver.Value{{true}}, nil
+	}
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+
+	sd := &panickingUnseed{newTestSeed("users", nil)}
+	sd.Config(sd.SeedData, sd.UnseedData)
+	s.AddSeed(sd)
+
+	err := s.Unseed()
+
+	var serr SeedError
+	if !errors.As(err, &serr) || serr.Name != "users" {
+		t.Fatalf("unseed error = %v, want a seed error naming the seed", err)
+	}
+
+	if f.rolledBack != 1 || f.open() != 0 {
+		t.Errorf("unseed transaction not reverted: %d rollbacks, %d open", f.rolledBack, f.open())
+	}
+}
+
+// cfgUnseed is a testSeed whose unseed function requires seeder config.
+type cfgUnseed struct {
+	*testSeed
+	cfg *Config
+}
+
+func (s *cfgUnseed) UnseedData() error {
+	s.cfg = s.GetCfg()
+	return nil
+}
+
+func TestUnseedSetsUpExecutor(t *testing.T) {
+	f, db := newFakeDB()
+	f.query = func(st string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		return []string{"is_applied"}, [][]driver.Value{{true}}, nil
+	}
+
+	s := newTestSeeder(map[string]string{"app.env": "test", "pg.schema": "sales"})
+	s.DB = db
+
+	sd := &cfgUnseed{testSeed: newTestSeed("users", nil)}
+	sd.Config(sd.SeedData, sd.UnseedData)
+
+	noUnseed := newTestSeed("accounts", nil)
+	noUnseed.Config(noUnseed.SeedData, nil)
+
+	s.RegisterSeeds(sd, noUnseed)
+
+	err := s.Unseed()
+	if err != nil {
+		t.Fatalf("unseed error: %v", err)
+	}
+
+	if sd.cfg != s.Cfg {
+		t.Error("unseed executor has no seeder config")
+	}
+
+	if n := len(f.stmts(`SET LOCAL search_path TO "sales"`)); n != 1 {
+		t.Errorf("got %d search path statements, want 1", n)
+	}
+
+	dels := f.stmts("DELETE FROM")
+	if len(dels) != 1 || dels[0].args[0] != "users" {
+		t.Errorf("deleted records = %v, want only the reverted seed", dels)
+	}
+}
+
+func TestParallelGroupErrors(t *testing.T) {
+	_, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test", "seed.parallelism": "2"})
+	s.DB = db
+
+	boom := errors.New("boom")
+	fail := func() error { return boom }
+	s.RegisterSeeds(&depSeed{newTestSeed("accounts", fail)}, &depSeed{newTestSeed("countries", fail)})
+
+	err := s.Seed()
+	if !errors.Is(err, boom) {
+		t.Errorf("seed error = %v, want it to wrap seed function error", err)
+	}
+
+	var serr SeedError
+	if !errors.As(err, &serr) {
+		t.Errorf("seed error = %v, want it to wrap a seed error", err)
+	}
+
+	for _, name := range []string{"'accounts'", "'countries'"} {
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("seed error = %v, want it to report %s", err, name)
+		}
+	}
+}
+
+func TestCloneSharesOwnedPools(t *testing.T) {
+	_, conn := newFakeDB()
+	_, admin := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.conn = conn
+	s.DB = conn
+	s.admin = admin
+
+	c := s.Clone()
+	if c.DB != conn || c.injected() {
+		t.Fatal("clone does not share seeder own connection")
+	}
+
+	if c.TotalSeeds() != 0 {
+		t.Errorf("clone without execs has %d seeds", c.TotalSeeds())
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("clone close error: %v", err)
+	}
+
+	for _, db := range []*sqlx.DB{conn, admin} {
+		if err := db.Ping(); err != nil {
+			t.Errorf("clone closed original pool: %v", err)
+		}
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	if err := conn.Ping(); err == nil {
+		t.Error("original pool left open")
+	}
+}
+
+func TestPendingCountFiltersEnvironment(t *testing.T) {
+	f, db := newFakeDB()
+	f.query = func(st string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		if strings.Contains(st, "SELECT is_applied") {
+			return []string{"is_applied"}, nil, nil
+		}
+		return []string{"exists"}, [][]driver.Value{{true}}, nil
+	}
+
+	s := newTestSeeder(map[string]string{"app.env": "test"})
+	s.DB = db
+	s.RegisterSeeds(newTestSeed("users", nil), &prodSeed{newTestSeed("accounts", nil)})
+
+	n, err := s.PendingCount()
+	if err != nil {
+		t.Fatalf("pending count error: %v", err)
+	}
+
+	pending, err := s.DryRun()
+	if err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+
+	if n != 1 || len(pending) != n {
+		t.Errorf("pending count = %d, dry run = %v, want only 'users'", n, pending)
+	}
+}
+
+func TestSetupWithoutAutoSetup(t *testing.T) {
+	f, db := newFakeDB()
+
+	s := newTestSeeder(map[string]string{"app.env": "test", "seed.autoSetup": "false"})
+	s.conn = db
+	s.DB = db
+
+	err := s.setup(context.Background())
+	if !errors.Is(err, ErrSeedTableMissing) {
+		t.Errorf("setup error = %v, want ErrSeedTableMissing", err)
+	}
+
+	if s.admin != nil {
+		t.Error("admin connection opened")
+	}
+
+	if n := len(f.stmts("")); n > 0 {
+		t.Errorf("%d statements executed, nothing should be created", n)
+	}
+}
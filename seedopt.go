@@ -0,0 +1,38 @@
+package kabestan
+
+import "time"
+
+type (
+	// SeederOption configures a Seeder on creation.
+	SeederOption func(s *Seeder)
+)
+
+// WithParallelism sets the max number of seeds
+// applied concurrently, it overrides
+// 'seed.parallelism' config value.
+func WithParallelism(n int) SeederOption {
+	return func(s *Seeder) {
+		s.parallelism = n
+	}
+}
+
+// WithTransactional runs all seeds in a single transaction.
+func WithTransactional() SeederOption {
+	return func(s *Seeder) {
+		s.Transactional = true
+	}
+}
+
+// WithClock sets the function used to timestamp seed records.
+func WithClock(now func() time.Time) SeederOption {
+	return func(s *Seeder) {
+		s.Now = now
+	}
+}
+
+// WithIDGen sets the function used to generate seed record IDs.
+func WithIDGen(gen func() string) SeederOption {
+	return func(s *Seeder) {
+		s.IDGen = gen
+	}
+}
@@ -0,0 +1,55 @@
+package kabestan
+
+import "sync"
+
+var (
+	// registry holds the seeds registered
+	// at package level, i.e.: from init functions.
+	registry struct {
+		sync.Mutex
+		execs []SeedExec
+	}
+)
+
+// RegisterSeed adds e to package seed registry
+// so that it can be loaded by any seeder using LoadRegistered.
+// It is intended to be called from seed file init function.
+func RegisterSeed(e SeedExec) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.execs = append(registry.execs, e)
+}
+
+// RegisteredSeeds returns the seeds in package registry
+// in registration order.
+func RegisteredSeeds() []SeedExec {
+	registry.Lock()
+	defer registry.Unlock()
+
+	execs := make([]SeedExec, len(registry.execs))
+	copy(execs, registry.execs)
+	return execs
+}
+
+// ClearRegistry removes all seeds from package registry.
+// Mostly useful in tests.
+func ClearRegistry() {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.execs = nil
+}
+
+// RegisterSeeds adds all execs to seeder seeds.
+func (s *Seeder) RegisterSeeds(execs ...SeedExec) {
+	for _, e := range execs {
+		s.AddSeed(e)
+	}
+}
+
+// LoadRegistered adds seeds in package registry to seeder seeds.
+// Executors are shared, not copied, by all seeders loading them.
+func (s *Seeder) LoadRegistered() {
+	s.RegisterSeeds(RegisteredSeeds()...)
+}
@@ -0,0 +1,66 @@
+//go:build go1.21
+// +build go1.21
+
+package kabestan
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger is a Logger backed by a slog.Logger.
+// First meta value is used as message,
+// remaining ones as key-value pairs.
+type SlogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger returns a Logger that writes to l.
+// slog default logger is used if l is nil.
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+
+	return &SlogLogger{l: l}
+}
+
+// Debug logs debug messages.
+func (s *SlogLogger) Debug(meta ...interface{}) {
+	s.log(slog.LevelDebug, meta)
+}
+
+// Info logs info messages.
+func (s *SlogLogger) Info(meta ...interface{}) {
+	s.log(slog.LevelInfo, meta)
+}
+
+// Warn logs warning messages.
+func (s *SlogLogger) Warn(meta ...interface{}) {
+	s.log(slog.LevelWarn, meta)
+}
+
+// Error logs error messages.
+// err is added under 'error' key.
+func (s *SlogLogger) Error(err error, meta ...interface{}) {
+	if err != nil {
+		s.log(slog.LevelError, meta, "error", err)
+		return
+	}
+	s.log(slog.LevelError, meta)
+}
+
+func (s *SlogLogger) log(level slog.Level, meta []interface{}, args ...interface{}) {
+	ctx := context.Background()
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+
+	var msg string
+	if len(meta) > 0 {
+		msg = stringify(meta[0])
+		meta = meta[1:]
+	}
+
+	s.l.Log(ctx, level, msg, append(meta, args...)...)
+}
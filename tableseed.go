@@ -0,0 +1,582 @@
+package kabestan
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type (
+	// Format of table seed data.
+	Format int
+
+	// ConflictAction taken when an inserted row
+	// conflicts with an existing one.
+	ConflictAction int
+
+	// TableSeedExec is a SeedExec that inserts
+	// CSV or JSON rows into a table.
+	TableSeedExec struct {
+		table string
+		r     io.Reader
+		// data read from r by first execution,
+		// kept so that the seed can be run again.
+		data   []byte
+		format Format
+		tx     *sqlx.Tx
+		ctx    context.Context
+		cfg    *Config
+		dia    dialect
+		hook   StatementHook
+		// Conflict handling
+		conflictCols   []string
+		conflictAction ConflictAction
+		// TruncateFirst empties the table, restarting
+		// its identity columns, before inserting the rows.
+		// It uses Postgres 'TRUNCATE TABLE' statement.
+		TruncateFirst bool
+		// TruncateCascade also truncates the tables
+		// referencing it, i.e.: using foreign keys.
+		TruncateCascade bool
+		// BinaryCols are the columns whose values are
+		// base64 encoded, they are decoded and bound as bytes,
+		// i.e.: for 'bytea' or 'blob' columns.
+		BinaryCols []string
+		// Inserted is the number of rows inserted
+		// by the last execution.
+		Inserted int64
+	}
+)
+
+const (
+	// CSV data, first record contains the column names.
+	CSV Format = iota
+	// JSON data, an array of objects whose keys are the column names.
+	JSON
+)
+
+const (
+	// DoNothing skips conflicting rows.
+	DoNothing ConflictAction = iota + 1
+	// DoUpdate overwrites conflicting rows.
+	DoUpdate
+)
+
+const (
+	tableSeedBatchSize = 500
+	// maxBindParams is the max number of
+	// parameters Postgres allows per statement.
+	maxBindParams = 65535
+)
+
+func (f Format) String() string {
+	switch f {
+	case CSV:
+		return "csv"
+	case JSON:
+		return "json"
+	default:
+		return fmt.Sprintf("format(%d)", int(f))
+	}
+}
+
+// NewTableSeedExec returns a seed executor that
+// inserts rows read from r into table.
+func NewTableSeedExec(table string, r io.Reader, format Format) *TableSeedExec {
+	return &TableSeedExec{
+		table:  table,
+		r:      r,
+		format: format,
+		ctx:    context.Background(),
+	}
+}
+
+// AddTableSeed registers a seed that inserts
+// rows read from r into table.
+// Data is read when first seeding and kept in memory
+// so that the seed can be run again, i.e.: when forced.
+// The executor is returned so that it can be further configured.
+func (s *Seeder) AddTableSeed(table string, r io.Reader, format Format) *TableSeedExec {
+	e := NewTableSeedExec(table, r, format)
+	s.AddSeed(e)
+	return e
+}
+
+// OnConflict makes inserts take action when a row
+// conflicts on cols with an existing one,
+// so that the seed can be safely re-run.
+// It generates a Postgres (and SQLite) 'ON CONFLICT' clause,
+// MySQL equivalent is 'ON DUPLICATE KEY UPDATE'
+// which is not supported yet, seeding fails if set.
+func (e *TableSeedExec) OnConflict(cols []string, action ConflictAction) *TableSeedExec {
+	e.conflictCols = cols
+	e.conflictAction = action
+	return e
+}
+
+// Config is a no-op, table seeds
+// always insert the rows read from its reader.
+func (e *TableSeedExec) Config(seed SeedFx, unseed SeedFx) {
+}
+
+// GetSeed returns the seed function.
+func (e *TableSeedExec) GetSeed() SeedFx {
+	return e.Seed
+}
+
+// GetUnseed returns the unseed function.
+func (e *TableSeedExec) GetUnseed() SeedFx {
+	return e.Unseed
+}
+
+// SetTx sets the transaction used to insert the rows.
+func (e *TableSeedExec) SetTx(tx *sqlx.Tx) {
+	e.tx = tx
+}
+
+// GetTx returns the executor transaction.
+func (e *TableSeedExec) GetTx() *sqlx.Tx {
+	return e.tx
+}
+
+// SetCtx sets the context used to insert the rows.
+func (e *TableSeedExec) SetCtx(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// RecordName is the table name followed by data format.
+func (e *TableSeedExec) RecordName() string {
+	return fmt.Sprintf("%s.%s", e.table, e.format)
+}
+
+// Seed reads the rows and inserts them in batches.
+func (e *TableSeedExec) Seed() error {
+	cols, rows, err := e.read()
+	if err != nil {
+		return fmt.Errorf("cannot read '%s' seed data: %w", e.table, err)
+	}
+
+	err = e.decodeBinary(cols, rows)
+	if err != nil {
+		return fmt.Errorf("cannot decode '%s' seed data: %w", e.table, err)
+	}
+
+	e.Inserted = 0
+
+	if e.TruncateFirst {
+		_, err = execSt(e.ctx, e.tx, e.hook, truncateSt(e.quote, e.table, e.TruncateCascade))
+		if err != nil {
+			return fmt.Errorf("cannot truncate '%s': %w", e.table, err)
+		}
+	}
+
+	size := e.batchSize(len(cols))
+
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		st, args, err := insertSt(e.dia, e.table, cols, rows[start:end])
+		if err != nil {
+			return fmt.Errorf("cannot insert '%s' seed data: %w", e.table, err)
+		}
+		st, err = e.onConflictSt(st, cols)
+		if err != nil {
+			return fmt.Errorf("cannot insert '%s' seed data: %w", e.table, err)
+		}
+
+		res, err := execSt(e.ctx, e.tx, e.hook, e.tx.Rebind(st), args...)
+		if err != nil {
+			return fmt.Errorf("cannot insert '%s' seed data: %w", e.table, err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			n = int64(end - start)
+		}
+		e.Inserted += n
+	}
+
+	return nil
+}
+
+// SetCfg sets the config used to read 'seed.batchSize'.
+func (e *TableSeedExec) SetCfg(cfg *Config) {
+	e.cfg = cfg
+}
+
+// batchSize returns the number of rows inserted per statement.
+func (e *TableSeedExec) batchSize(cols int) int {
+	return batchSize(e.cfg, cols)
+}
+
+// batchSize returns the number of rows of cols columns inserted
+// per statement. It is read from 'seed.batchSize' config value but
+// lowered if needed so that bound parameters stay under Postgres limit.
+func batchSize(cfg *Config, cols int) int {
+	size := tableSeedBatchSize
+	if cfg != nil {
+		size = int(cfg.ValAsInt("seed.batchSize", tableSeedBatchSize))
+	}
+
+	if cols > 0 && size*cols > maxBindParams {
+		size = maxBindParams / cols
+	}
+
+	if size < 1 {
+		size = 1
+	}
+
+	return size
+}
+
+// setDialect sets the dialect used to quote identifiers.
+func (e *TableSeedExec) setDialect(d dialect) {
+	e.dia = d
+}
+
+// setStatementHook sets the hook called after each statement.
+func (e *TableSeedExec) setStatementHook(h StatementHook) {
+	e.hook = h
+}
+
+// quote quotes name as an identifier using executor dialect.
+func (e *TableSeedExec) quote(name string) string {
+	return dialectQuote(e.dia, name)
+}
+
+// dialectQuote quotes name as an identifier using d,
+// Postgres double quotes are used if d is nil.
+func dialectQuote(d dialect, name string) string {
+	if d == nil {
+		return quoteIdent(name)
+	}
+	return d.QuoteIdent(name)
+}
+
+// RowsAffected returns the rows inserted by last execution.
+func (e *TableSeedExec) RowsAffected() int64 {
+	return e.Inserted
+}
+
+// Unseed is a no-op, inserted rows are not removed.
+// Only its record is removed from seeder table.
+func (e *TableSeedExec) Unseed() error {
+	return nil
+}
+
+// onConflictSt appends the conflict clause to insert statement st.
+// DoUpdate sets all non conflict columns to the inserted values,
+// if there are none it behaves as DoNothing.
+// MySQL is not supported and an error is returned.
+func (e *TableSeedExec) onConflictSt(st string, cols []string) (string, error) {
+	if e.conflictAction == 0 || len(e.conflictCols) == 0 {
+		return st, nil
+	}
+
+	if _, ok := e.dia.(*mysqlDialect); ok {
+		return "", errors.New("on conflict clause not supported by 'mysql' engine")
+	}
+
+	cc := make([]string, len(e.conflictCols))
+	isConflict := map[string]bool{}
+	for i, c := range e.conflictCols {
+		cc[i] = e.quote(c)
+		isConflict[c] = true
+	}
+
+	var sets []string
+	if e.conflictAction == DoUpdate {
+		for _, c := range cols {
+			if !isConflict[c] {
+				sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", e.quote(c), e.quote(c)))
+			}
+		}
+	}
+
+	action := "DO NOTHING"
+	if len(sets) > 0 {
+		action = "DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+
+	return fmt.Sprintf("%s ON CONFLICT (%s) %s;", strings.TrimSuffix(st, ";"), strings.Join(cc, ", "), action), nil
+}
+
+// decodeBinary replaces base64 encoded values of
+// binary columns by the bytes they represent.
+// Null values are kept.
+func (e *TableSeedExec) decodeBinary(cols []string, rows [][]interface{}) error {
+	isBinary := map[string]bool{}
+	for _, c := range e.BinaryCols {
+		isBinary[c] = true
+	}
+
+	for i, c := range cols {
+		if !isBinary[c] {
+			continue
+		}
+
+		for n, row := range rows {
+			if row[i] == nil {
+				continue
+			}
+
+			enc, ok := row[i].(string)
+			if !ok {
+				return fmt.Errorf("column '%s' row %d: binary value is not a string", c, n+1)
+			}
+
+			b, err := base64.StdEncoding.DecodeString(enc)
+			if err != nil {
+				return fmt.Errorf("column '%s' row %d: %w", c, n+1, err)
+			}
+			row[i] = b
+		}
+	}
+
+	return nil
+}
+
+// read parses executor data, it is read from
+// executor reader only the first time.
+func (e *TableSeedExec) read() (cols []string, rows [][]interface{}, err error) {
+	if e.data == nil {
+		data, err := io.ReadAll(e.r)
+		if err != nil {
+			return nil, nil, err
+		}
+		e.data = data
+	}
+
+	switch e.format {
+	case CSV:
+		return readCSV(bytes.NewReader(e.data))
+	case JSON:
+		return readJSON(bytes.NewReader(e.data))
+	default:
+		return nil, nil, fmt.Errorf("unsupported seed data format: %s", e.format)
+	}
+}
+
+// readCSV reads CSV records using first one as column names.
+func readCSV(r io.Reader) (cols []string, rows [][]interface{}, err error) {
+	recs, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(recs) == 0 {
+		return nil, nil, nil
+	}
+
+	cols = recs[0]
+	for _, rec := range recs[1:] {
+		row := make([]interface{}, len(rec))
+		for i, v := range rec {
+			row[i] = v
+		}
+		rows = append(rows, row)
+	}
+
+	return cols, rows, nil
+}
+
+// readJSON reads an array of JSON objects.
+// Columns are the union of all object keys sorted by name,
+// missing keys are inserted as NULL.
+func readJSON(r io.Reader) (cols []string, rows [][]interface{}, err error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var objs []map[string]interface{}
+	err = dec.Decode(&objs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cols, rows = mapRows(objs)
+	return cols, rows, nil
+}
+
+// mapRows converts objs into rows of values.
+// Columns are the union of all object keys sorted by name,
+// missing keys are set to nil.
+func mapRows(objs []map[string]interface{}) (cols []string, rows [][]interface{}) {
+	keys := map[string]bool{}
+	for _, o := range objs {
+		for k := range o {
+			keys[k] = true
+		}
+	}
+
+	for k := range keys {
+		cols = append(cols, k)
+	}
+	sort.Strings(cols)
+
+	for _, o := range objs {
+		row := make([]interface{}, len(cols))
+		for i, c := range cols {
+			row[i] = o[c]
+		}
+		rows = append(rows, row)
+	}
+
+	return cols, rows
+}
+
+// bindValue returns v as an insert argument for dialect d
+// and the cast required by its placeholder, if any.
+// Slices of scalar values are bound as Postgres arrays,
+// other engines don't support them, map, struct and slices
+// of other values as JSON, cast to jsonb for Postgres.
+// Valuers and bytes are bound as they are
+// and json.RawMessage as JSON.
+func bindValue(d dialect, v interface{}) (arg interface{}, cast string, err error) {
+	if v == nil {
+		return nil, "", nil
+	}
+
+	if _, ok := v.(driver.Valuer); ok {
+		return v, "", nil
+	}
+
+	if raw, ok := v.(json.RawMessage); ok {
+		return jsonValue(d, string(raw))
+	}
+
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v, "", nil
+		}
+
+		if !scalarSlice(rv) {
+			break
+		}
+
+		if !isPostgres(d) {
+			return nil, "", fmt.Errorf("array values not supported by '%s' engine", d.DriverName())
+		}
+		return pq.Array(v), "", nil
+
+	case reflect.Map, reflect.Struct:
+		if _, ok := v.(time.Time); ok {
+			return v, "", nil
+		}
+
+	default:
+		return v, "", nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot encode JSON value: %w", err)
+	}
+
+	return jsonValue(d, string(b))
+}
+
+// jsonValue returns JSON text s as an insert argument
+// for dialect d, cast to jsonb for Postgres.
+func jsonValue(d dialect, s string) (arg interface{}, cast string, err error) {
+	if !isPostgres(d) {
+		return s, "", nil
+	}
+	return s, "::jsonb", nil
+}
+
+// scalarSlice tells if all the elements of slice rv
+// are strings, numbers or booleans.
+func scalarSlice(rv reflect.Value) bool {
+	for i := 0; i < rv.Len(); i++ {
+		e := rv.Index(i)
+		if e.Kind() == reflect.Interface {
+			if e.IsNil() {
+				continue
+			}
+			e = e.Elem()
+		}
+
+		switch e.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// isPostgres tells if d is Postgres dialect,
+// the one used if d is nil.
+func isPostgres(d dialect) bool {
+	if d == nil {
+		return true
+	}
+
+	_, ok := d.(*pgDialect)
+	return ok
+}
+
+// truncateSt builds a truncate statement
+// that also restarts table identity columns.
+func truncateSt(quote func(string) string, table string, cascade bool) string {
+	st := fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY", quoteQualIdent(quote, table))
+	if cascade {
+		st += " CASCADE"
+	}
+	return st + ";"
+}
+
+// insertSt builds a multi row parameterized insert statement
+// for dialect d using '?' placeholders,
+// it should be rebound before execution.
+// Values are bound as described by bindValue.
+func insertSt(d dialect, table string, cols []string, rows [][]interface{}) (st string, args []interface{}, err error) {
+	quote := func(name string) string {
+		return dialectQuote(d, name)
+	}
+
+	qc := make([]string, len(cols))
+	for i, c := range cols {
+		qc[i] = quote(c)
+	}
+
+	vals := make([]string, len(rows))
+	for i, row := range rows {
+		ph := make([]string, len(row))
+		for j, v := range row {
+			arg, cast, err := bindValue(d, v)
+			if err != nil {
+				return "", nil, fmt.Errorf("column '%s': %w", cols[j], err)
+			}
+			ph[j] = "?" + cast
+			args = append(args, arg)
+		}
+		vals[i] = "(" + strings.Join(ph, ", ") + ")"
+	}
+
+	st = fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", quoteQualIdent(quote, table), strings.Join(qc, ", "), strings.Join(vals, ", "))
+	return st, args, nil
+}
@@ -0,0 +1,198 @@
+package kabestan
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTableSeedReadTwice(t *testing.T) {
+	tests := []struct {
+		format Format
+		data   string
+	}{
+		{CSV, "id,name\n1,one\n2,two\n"},
+		{JSON, `[{"id": 1, "name": "one"}, {"id": 2, "name": "two"}]`},
+	}
+
+	for _, tt := range tests {
+		e := NewTableSeedExec("items", strings.NewReader(tt.data), tt.format)
+
+		for run := 1; run <= 2; run++ {
+			cols, rows, err := e.read()
+			if err != nil {
+				t.Fatalf("%s run %d: read error: %v", tt.format, run, err)
+			}
+
+			if len(cols) != 2 || len(rows) != 2 {
+				t.Errorf("%s run %d: got %d columns and %d rows, want 2 and 2", tt.format, run, len(cols), len(rows))
+			}
+		}
+	}
+}
+
+func TestInsertStArrayAndJSON(t *testing.T) {
+	rows := [][]interface{}{{[]string{"a", "b"}, map[string]interface{}{"k": 1}, "x"}}
+	cols := []string{"tags", "attrs", "name"}
+
+	st, args, err := insertSt(&pgDialect{}, "public.items", cols, rows)
+	if err != nil {
+		t.Fatalf("insert error: %v", err)
+	}
+
+	want := `INSERT INTO "public"."items" ("tags", "attrs", "name") VALUES (?, ?::jsonb, ?);`
+	if st != want {
+		t.Errorf("statement = %s, want %s", st, want)
+	}
+
+	if len(args) != 3 {
+		t.Fatalf("got %d arguments, want 3", len(args))
+	}
+
+	if _, ok := args[0].(driver.Valuer); !ok {
+		t.Errorf("array argument %T is not a Postgres array", args[0])
+	}
+
+	if args[1] != `{"k":1}` {
+		t.Errorf("JSON argument = %v", args[1])
+	}
+}
+
+func TestInsertStNonPostgres(t *testing.T) {
+	cols := []string{"attrs"}
+
+	st, args, err := insertSt(&mysqlDialect{}, "items", cols, [][]interface{}{{map[string]interface{}{"k": 1}}})
+	if err != nil {
+		t.Fatalf("insert error: %v", err)
+	}
+
+	if strings.Contains(st, "::jsonb") || args[0] != `{"k":1}` {
+		t.Errorf("JSON bound as Postgres jsonb for MySQL: %s %v", st, args)
+	}
+
+	_, _, err = insertSt(&sqliteDialect{}, "items", cols, [][]interface{}{{[]int{1, 2}}})
+	if err == nil {
+		t.Error("expected an error for array value in SQLite")
+	}
+}
+
+func TestOnConflictSt(t *testing.T) {
+	cols := []string{"code", "name"}
+	st := `INSERT INTO "countries" ("code", "name") VALUES (?, ?);`
+
+	e := NewTableSeedExec("countries", strings.NewReader(""), CSV).OnConflict([]string{"code"}, DoUpdate)
+	e.dia = &pgDialect{}
+
+	got, err := e.onConflictSt(st, cols)
+	if err != nil {
+		t.Fatalf("on conflict error: %v", err)
+	}
+
+	want := `INSERT INTO "countries" ("code", "name") VALUES (?, ?) ON CONFLICT ("code") DO UPDATE SET "name" = EXCLUDED."name";`
+	if got != want {
+		t.Errorf("statement = %s, want %s", got, want)
+	}
+
+	e.dia = &mysqlDialect{}
+	if _, err := e.onConflictSt(st, cols); err == nil {
+		t.Error("expected an error for MySQL")
+	}
+}
+
+func TestBatchSizeBindLimit(t *testing.T) {
+	tests := []struct {
+		cols int
+		want int
+	}{
+		{200, 327},
+		{1, tableSeedBatchSize},
+		{maxBindParams + 1, 1},
+	}
+
+	for _, tt := range tests {
+		if got := batchSize(nil, tt.cols); got != tt.want {
+			t.Errorf("batchSize(%d) = %d, want %d", tt.cols, got, tt.want)
+		}
+	}
+
+	cfg := testConfig(map[string]string{"seed.batchSize": "10"})
+	if got := batchSize(cfg, 3); got != 10 {
+		t.Errorf("configured batch size = %d, want 10", got)
+	}
+}
+
+func TestDecodeBinary(t *testing.T) {
+	data := []byte{0x00, 0xff, 'k', 'b'}
+
+	e := NewTableSeedExec("files", strings.NewReader(""), CSV)
+	e.BinaryCols = []string{"content"}
+
+	cols := []string{"name", "content"}
+	rows := [][]interface{}{
+		{"a.bin", base64.StdEncoding.EncodeToString(data)},
+		{"empty", nil},
+	}
+
+	err := e.decodeBinary(cols, rows)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if b, ok := rows[0][1].([]byte); !ok || !bytes.Equal(b, data) {
+		t.Errorf("decoded value = %v, want %v", rows[0][1], data)
+	}
+
+	if rows[1][1] != nil || rows[0][0] != "a.bin" {
+		t.Errorf("non binary or null values changed: %v", rows)
+	}
+
+	err = e.decodeBinary(cols, [][]interface{}{{"bad", "not base64!"}})
+	if err == nil {
+		t.Error("expected an error for invalid base64 value")
+	}
+}
+
+func TestBindValueJSONArrays(t *testing.T) {
+	objs := []interface{}{map[string]interface{}{"k": 1}}
+	raw := json.RawMessage(`[{"k":1}]`)
+	bin := []byte{0x01, 0x02}
+
+	tests := []struct {
+		d    dialect
+		v    interface{}
+		arg  interface{}
+		cast string
+	}{
+		{&pgDialect{}, objs, `[{"k":1}]`, "::jsonb"},
+		{&pgDialect{}, raw, `[{"k":1}]`, "::jsonb"},
+		{&mysqlDialect{}, objs, `[{"k":1}]`, ""},
+		{&sqliteDialect{}, raw, `[{"k":1}]`, ""},
+	}
+
+	for _, tt := range tests {
+		arg, cast, err := bindValue(tt.d, tt.v)
+		if err != nil {
+			t.Errorf("bind %T for %s error: %v", tt.v, tt.d.DriverName(), err)
+			continue
+		}
+
+		if arg != tt.arg || cast != tt.cast {
+			t.Errorf("bind %T for %s = %v%s, want %v%s", tt.v, tt.d.DriverName(), arg, cast, tt.arg, tt.cast)
+		}
+	}
+
+	for _, d := range []dialect{&pgDialect{}, &mysqlDialect{}} {
+		arg, cast, err := bindValue(d, bin)
+		if b, ok := arg.([]byte); err != nil || !ok || !bytes.Equal(b, bin) || cast != "" {
+			t.Errorf("bytes for %s bound as %v%s, %v", d.DriverName(), arg, cast, err)
+		}
+	}
+
+	arg, _, err := bindValue(&pgDialect{}, []interface{}{"a", "b"})
+	if _, ok := arg.(driver.Valuer); err != nil || !ok {
+		t.Errorf("scalar slice bound as %T, want a Postgres array", arg)
+	}
+}